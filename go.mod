@@ -3,7 +3,7 @@ module github.com/DevopsArtFactory/goployer
 go 1.14
 
 require (
-	github.com/aws/aws-sdk-go v1.33.6
+	github.com/aws/aws-sdk-go v1.43.6
 	github.com/fatih/color v1.9.0
 	github.com/sirupsen/logrus v1.6.0
 	github.com/slack-go/slack v0.6.4
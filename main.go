@@ -2,14 +2,18 @@ package main
 
 import (
 	"github.com/DevopsArtFactory/goployer/pkg/runner"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
 	Logger "github.com/sirupsen/logrus"
-	"os"
 )
 
 func main() {
+	// Exit hooks like resuming processes should run even if goployer is stopped
+	tool.HandleExitSignals()
+
 	//Create new builder
 	if err := runner.Start(); err != nil {
 		Logger.Error(err.Error())
-		os.Exit(1)
+		tool.SetExitReason(err.Error())
+		tool.Exit(1)
 	}
 }
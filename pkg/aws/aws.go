@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
@@ -8,16 +9,22 @@ import (
 )
 
 var (
-	DEFAULT_HEALTHCHECK_TYPE         = "EC2"
-	DEFAULT_HEALTHCHECK_GRACE_PERIOD = 300
+	AMI_COPY_SOURCE_TAG          = "goployer:source-ami"
+	AMI_COPY_SOURCE_REGION_TAG   = "goployer:source-region"
+	AMI_COPY_KMS_KEY_TAG         = "goployer:kms-key-id"
+	AMI_COPY_WAITER_MAX_ATTEMPTS = 120
+	AMI_NAME_MAX_LENGTH          = 128
 )
 
 type AWSClient struct {
 	Region            string
 	EC2Service        EC2Client
 	ELBService        ELBV2Client
+	ClassicELBService ELBClient
 	CloudWatchService CloudWatchClient
 	SSMService        SSMClient
+	IAMService        IAMClient
+	LambdaService     LambdaClient
 }
 
 type MetricClient struct {
@@ -56,8 +63,11 @@ func BootstrapServices(region string, assume_role string) AWSClient {
 		Region:            region,
 		EC2Service:        NewEC2Client(aws_session, region, creds),
 		ELBService:        NewELBV2Client(aws_session, region, creds),
+		ClassicELBService: NewELBClient(aws_session, region, creds),
 		CloudWatchService: NewCloudWatchClient(aws_session, region, creds),
 		SSMService:        NewSSMClient(aws_session, region, creds),
+		IAMService:        NewIAMClient(aws_session, region, creds),
+		LambdaService:     NewLambdaClient(aws_session, region, creds),
 	}
 
 	return client
@@ -79,3 +89,17 @@ func BootstrapMetricService(region string, assume_role string) MetricClient {
 
 	return client
 }
+
+// ResolveAmi returns AMI id in the region
+// If ami id is not specified, then AMI is selected by the selector.
+func (a AWSClient) ResolveAmi(ami string, selector builder.AmiSelector) (string, error) {
+	if len(ami) > 0 {
+		return ami, nil
+	}
+
+	if len(selector.SSMParameter) > 0 {
+		return a.SSMService.GetParameter(selector.SSMParameter)
+	}
+
+	return a.EC2Service.FindLatestImage(selector.Name, selector.Owners, selector.Tags)
+}
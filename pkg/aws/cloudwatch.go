@@ -9,6 +9,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	Logger "github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"time"
 )
 
 type CloudWatchClient struct {
@@ -36,11 +39,9 @@ func (c CloudWatchClient) CreateScalingAlarms(asg_name string, alarms []builder.
 
 	//Create cloudwatch alarms
 	for _, alarm := range alarms {
-		arns := []string{}
-		for _, action := range alarm.AlarmActions {
-			arns = append(arns, policyArns[action])
-		}
-		alarm.AlarmActions = arns
+		alarm.AlarmActions = makeAlarmActions(alarm.AlarmActions, policyArns)
+		alarm.OKActions = makeAlarmActions(alarm.OKActions, policyArns)
+		alarm.InsufficientDataActions = makeAlarmActions(alarm.InsufficientDataActions, policyArns)
 		if err := c.CreateCloudWatchAlarm(asg_name, alarm); err != nil {
 			return err
 		}
@@ -49,24 +50,66 @@ func (c CloudWatchClient) CreateScalingAlarms(asg_name string, alarms []builder.
 	return nil
 }
 
+// makeAlarmActions changes names of scaling policies to ARNs
+// ARN like SNS topic is used as it is.
+func makeAlarmActions(actions []string, policyArns map[string]string) []string {
+	arns := []string{}
+	for _, action := range actions {
+		if builder.IsArn(action) {
+			arns = append(arns, action)
+			continue
+		}
+		arns = append(arns, policyArns[action])
+	}
+
+	return arns
+}
+
+// GetAlarmName returns name of the alarm for autoscaling group
+// Alarms are namespaced by autoscaling group so that every version has its own alarms.
+func GetAlarmName(asg_name, alarm string) string {
+	return fmt.Sprintf("%s-%s", asg_name, alarm)
+}
+
 // Create cloudwatch alarms for autoscaling group
 func (c CloudWatchClient) CreateCloudWatchAlarm(asg_name string, alarm builder.AlarmConfigs) error {
+	name := GetAlarmName(asg_name, alarm.Name)
 	input := &cloudwatch.PutMetricAlarmInput{
-		AlarmName:          aws.String(alarm.Name),
+		AlarmName:          aws.String(name),
 		AlarmActions:       MakeStringArrayToAwsStrings(alarm.AlarmActions),
-		MetricName:         aws.String(alarm.Metric),
-		Namespace:          aws.String(alarm.Namespace),
-		Statistic:          aws.String(alarm.Statistic),
 		ComparisonOperator: aws.String(alarm.Comparison),
 		Threshold:          aws.Float64(alarm.Threshold),
-		Period:             aws.Int64(alarm.Period),
 		EvaluationPeriods:  aws.Int64(alarm.EvaluationPeriods),
-		Dimensions: []*cloudwatch.Dimension{
-			{
-				Name:  aws.String("AutoScalingGroupName"),
-				Value: aws.String(asg_name),
-			},
-		},
+	}
+
+	if len(alarm.OKActions) > 0 {
+		input.OKActions = MakeStringArrayToAwsStrings(alarm.OKActions)
+	}
+
+	if len(alarm.InsufficientDataActions) > 0 {
+		input.InsufficientDataActions = MakeStringArrayToAwsStrings(alarm.InsufficientDataActions)
+	}
+
+	if len(alarm.TreatMissingData) > 0 {
+		input.TreatMissingData = aws.String(alarm.TreatMissingData)
+	}
+
+	if alarm.DatapointsToAlarm > 0 {
+		input.DatapointsToAlarm = aws.Int64(alarm.DatapointsToAlarm)
+	}
+
+	if len(alarm.Metrics) > 0 {
+		input.Metrics = makeMetricDataQueries(alarm.Metrics)
+	} else {
+		input.MetricName = aws.String(alarm.Metric)
+		input.Namespace = aws.String(alarm.Namespace)
+		input.Period = aws.Int64(alarm.Period)
+		input.Dimensions = makeDimensions(alarm.Dimensions)
+		if builder.IsExtendedStatistic(alarm.Statistic) {
+			input.ExtendedStatistic = aws.String(alarm.Statistic)
+		} else {
+			input.Statistic = aws.String(alarm.Statistic)
+		}
 	}
 
 	_, err := c.Client.PutMetricAlarm(input)
@@ -86,7 +129,149 @@ func (c CloudWatchClient) CreateCloudWatchAlarm(asg_name string, alarm builder.A
 		return err
 	}
 
-	Logger.Info(fmt.Sprintf("New metric alarm is created : %s / asg : %s", alarm.Name, asg_name))
+	Logger.Info(fmt.Sprintf("New metric alarm is created : %s / asg : %s", name, asg_name))
+
+	return nil
+}
+
+// makeMetricDataQueries makes queries of metric math alarm
+func makeMetricDataQueries(metrics []builder.AlarmMetric) []*cloudwatch.MetricDataQuery {
+	queries := []*cloudwatch.MetricDataQuery{}
+	for _, m := range metrics {
+		query := &cloudwatch.MetricDataQuery{
+			Id:         aws.String(m.Id),
+			ReturnData: aws.Bool(m.ReturnData),
+		}
+
+		if len(m.Label) > 0 {
+			query.Label = aws.String(m.Label)
+		}
+
+		if len(m.Expression) > 0 {
+			query.Expression = aws.String(m.Expression)
+		} else {
+			query.MetricStat = &cloudwatch.MetricStat{
+				Metric: &cloudwatch.Metric{
+					Namespace:  aws.String(m.Namespace),
+					MetricName: aws.String(m.Metric),
+					Dimensions: makeDimensions(m.Dimensions),
+				},
+				Period: aws.Int64(m.Period),
+				Stat:   aws.String(m.Statistic),
+			}
+		}
+
+		queries = append(queries, query)
+	}
+
+	return queries
+}
+
+// makeDimensions makes cloudwatch dimensions from "key=value" strings
+func makeDimensions(dimensions []string) []*cloudwatch.Dimension {
+	ret := []*cloudwatch.Dimension{}
+	for _, dimension := range dimensions {
+		kv := strings.SplitN(dimension, "=", 2)
+		ret = append(ret, &cloudwatch.Dimension{
+			Name:  aws.String(kv[0]),
+			Value: aws.String(kv[1]),
+		})
+	}
+
+	return ret
+}
+
+// DeleteAlarmsOfAutoscalingGroup deletes all alarms created for the autoscaling group
+func (c CloudWatchClient) DeleteAlarmsOfAutoscalingGroup(asg_name string) error {
+	names := []*string{}
+	input := &cloudwatch.DescribeAlarmsInput{
+		AlarmNamePrefix: aws.String(GetAlarmName(asg_name, "")),
+	}
+
+	err := c.Client.DescribeAlarmsPages(input, func(page *cloudwatch.DescribeAlarmsOutput, lastPage bool) bool {
+		for _, alarm := range page.MetricAlarms {
+			names = append(names, alarm.AlarmName)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	// DeleteAlarms accepts up to 100 alarms at once
+	for i := 0; i < len(names); i += 100 {
+		end := i + 100
+		if end > len(names) {
+			end = len(names)
+		}
+
+		if _, err := c.Client.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{AlarmNames: names[i:end]}); err != nil {
+			return err
+		}
+	}
+
+	if len(names) > 0 {
+		Logger.Info(fmt.Sprintf("Metric alarms are deleted : %d / asg : %s", len(names), asg_name))
+	}
 
 	return nil
 }
+
+// GetMetricDatapoints returns datapoints of the health gate metric in time order
+// Dimensions should be like "key=value".
+func (c CloudWatchClient) GetMetricDatapoints(gate builder.HealthGate, dimensions []string, start, end time.Time) ([]float64, error) {
+	period := gate.Period
+	if period == 0 {
+		period = builder.DEFAULT_HEALTH_GATE_PERIOD
+	}
+
+	input := &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String(gate.Namespace),
+		MetricName: aws.String(gate.Metric),
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int64(period),
+	}
+
+	input.Dimensions = makeDimensions(dimensions)
+
+	if builder.IsExtendedStatistic(gate.Statistic) {
+		input.ExtendedStatistics = []*string{aws.String(gate.Statistic)}
+	} else {
+		input.Statistics = []*string{aws.String(gate.Statistic)}
+	}
+
+	result, err := c.Client.GetMetricStatistics(input)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result.Datapoints, func(i, j int) bool {
+		return result.Datapoints[i].Timestamp.Before(*result.Datapoints[j].Timestamp)
+	})
+
+	ret := []float64{}
+	for _, dp := range result.Datapoints {
+		var value *float64
+		switch gate.Statistic {
+		case "Average":
+			value = dp.Average
+		case "Sum":
+			value = dp.Sum
+		case "Minimum":
+			value = dp.Minimum
+		case "Maximum":
+			value = dp.Maximum
+		case "SampleCount":
+			value = dp.SampleCount
+		default:
+			value = dp.ExtendedStatistics[gate.Statistic]
+		}
+
+		if value != nil {
+			ret = append(ret, *value)
+		}
+	}
+
+	return ret, nil
+}
@@ -1,19 +1,22 @@
 package aws
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/DevopsArtFactory/goployer/pkg/builder"
 	"github.com/DevopsArtFactory/goployer/pkg/tool"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	Logger "github.com/sirupsen/logrus"
-	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
 type EC2Client struct {
@@ -125,6 +128,32 @@ func (e EC2Client) DeleteAutoscalingSet(asg_name string) bool {
 	return true
 }
 
+// GetScalingActivities returns recent scaling activities of the autoscaling group
+func (e EC2Client) GetScalingActivities(asg_name string) ([]*autoscaling.Activity, error) {
+	input := &autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(asg_name),
+		MaxRecords:           aws.Int64(100),
+	}
+
+	result, err := e.AsClient.DescribeScalingActivities(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Activities, nil
+}
+
+// ForceDeleteAutoscalingGroup deletes autoscaling group with instances in it
+func (e EC2Client) ForceDeleteAutoscalingGroup(asg_name string) error {
+	input := &autoscaling.DeleteAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(asg_name),
+		ForceDelete:          aws.Bool(true),
+	}
+
+	_, err := e.AsClient.DeleteAutoScalingGroup(input)
+	return err
+}
+
 // Get All matching autoscaling groups with aws prefix
 // By this function, you could get the latest version of deployment
 func (e EC2Client) GetAllMatchingAutoscalingGroupsWithPrefix(prefix string) []*autoscaling.Group {
@@ -379,57 +408,65 @@ func (e EC2Client) GetSecurityGroupList(vpc string, sgList []string) []*string {
 
 	var retList []*string
 	for _, sg := range sgList {
-		if strings.HasPrefix(sg, "sg-") {
-			retList = append(retList, aws.String(sg))
-			continue
+		sgId, err := e.FindSecurityGroupId(vpcId, sg)
+		if err != nil {
+			tool.ErrorLogging(err.Error())
 		}
 
-		input := &ec2.DescribeSecurityGroupsInput{
-			Filters: []*ec2.Filter{
-				{
-					Name: aws.String("group-name"),
-					Values: []*string{
-						aws.String(sg),
-					},
+		retList = append(retList, aws.String(sgId))
+	}
+
+	return retList
+}
+
+// FindSecurityGroupId returns the id of security group with the name in the VPC
+func (e EC2Client) FindSecurityGroupId(vpcId, sg string) (string, error) {
+	if strings.HasPrefix(sg, "sg-") {
+		return sg, nil
+	}
+
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name: aws.String("group-name"),
+				Values: []*string{
+					aws.String(sg),
 				},
-				{
-					Name: aws.String("vpc-id"),
-					Values: []*string{
-						aws.String(vpcId),
-					},
+			},
+			{
+				Name: aws.String("vpc-id"),
+				Values: []*string{
+					aws.String(vpcId),
 				},
 			},
-		}
+		},
+	}
 
-		result, err := e.Client.DescribeSecurityGroups(input)
-		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				default:
-					Logger.Errorln(aerr.Error())
-				}
-			} else {
-				// Print the error, cast err to awserr.Error to get the Code and
-				// Message from an error.
-				Logger.Errorln(err.Error())
+	result, err := e.Client.DescribeSecurityGroups(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			default:
+				Logger.Errorln(aerr.Error())
 			}
-
-			os.Exit(1)
+		} else {
+			// Print the error, cast err to awserr.Error to get the Code and
+			// Message from an error.
+			Logger.Errorln(err.Error())
 		}
+		return "", err
+	}
 
-		//If it matches 0 or more than 1, it is wrong
-		if len(result.SecurityGroups) != 1 {
-			matched := []string{}
-			for _, s := range result.SecurityGroups {
-				matched = append(matched, *s.GroupName)
-			}
-			tool.ErrorLogging(fmt.Sprintf("Expected only one security group on name lookup for \"%s\" got \"%s\"", sg, strings.Join(matched, ",")))
+	//If it matches 0 or more than 1, it is wrong
+	if len(result.SecurityGroups) != 1 {
+		matched := []string{}
+		for _, s := range result.SecurityGroups {
+			matched = append(matched, *s.GroupName)
 		}
-
-		retList = append(retList, aws.String(*result.SecurityGroups[0].GroupId))
+		return "", fmt.Errorf("Expected only one security group on name lookup for \"%s\" got \"%s\"", sg, strings.Join(matched, ","))
 	}
 
-	return retList
+	return *result.SecurityGroups[0].GroupId, nil
 }
 
 // MakeBlockDevices returns list of block device mapping for launch configuration
@@ -495,14 +532,23 @@ func (e EC2Client) MakeLaunchTemplateBlockDeviceMappings(blocks []builder.BlockD
 }
 
 func (e EC2Client) GetVPCId(vpc string) string {
+	vpcId, err := e.FindVPCId(vpc)
+	if err != nil {
+		tool.ErrorLogging(err.Error())
+	}
+
+	return vpcId
+}
+
+// FindVPCId returns the id of VPC with the name
+func (e EC2Client) FindVPCId(vpc string) (string, error) {
 	ret, err := regexp.MatchString("vpc-[0-9A-Fa-f]{17}", vpc)
 	if err != nil {
-		fmt.Errorf("Error occurs when checking regex %v", err.Error())
-		os.Exit(1)
+		return "", fmt.Errorf("Error occurs when checking regex %v", err.Error())
 	}
 
 	if ret {
-		return vpc
+		return vpc, nil
 	}
 
 	input := &ec2.DescribeVpcsInput{
@@ -528,24 +574,25 @@ func (e EC2Client) GetVPCId(vpc string) string {
 			// Message from an error.
 			Logger.Errorln(err.Error())
 		}
-		os.Exit(1)
+		return "", err
 	}
 
 	// More than 1 vpc..
 	if len(result.Vpcs) > 1 {
-		tool.ErrorLogging(fmt.Sprintf("Expected only one VPC on name lookup for %v", vpc))
+		return "", fmt.Errorf("Expected only one VPC on name lookup for %v", vpc)
 	}
 
 	// No VPC found
 	if len(result.Vpcs) < 1 {
-		tool.ErrorLogging(fmt.Sprintf("Unable to find VPC on name lookup for %v", vpc))
+		return "", fmt.Errorf("Unable to find VPC on name lookup for %v", vpc)
 	}
 
-	return *result.Vpcs[0].VpcId
+	return *result.Vpcs[0].VpcId, nil
 }
 
 func (e EC2Client) CreateAutoScalingGroup(name, launch_template_name, healthcheck_type string,
-	healthcheck_grace_period int64,
+	healthcheck_grace_period, default_cooldown, max_instance_lifetime int64,
+	capacity_rebalance bool,
 	capacity builder.Capacity,
 	loadbalancers, target_group_arns, termination_policies, availability_zones []*string,
 	tags []*(autoscaling.Tag),
@@ -567,13 +614,22 @@ func (e EC2Client) CreateAutoScalingGroup(name, launch_template_name, healthchec
 		TerminationPolicies:    termination_policies,
 		Tags:                   tags,
 		VPCZoneIdentifier:      aws.String(strings.Join(subnets, ",")),
+		CapacityRebalance:      aws.Bool(capacity_rebalance),
+	}
+
+	if default_cooldown > 0 {
+		input.DefaultCooldown = aws.Int64(default_cooldown)
+	}
+
+	if max_instance_lifetime > 0 {
+		input.MaxInstanceLifetime = aws.Int64(max_instance_lifetime)
 	}
 
-	if *loadbalancers[0] != "" {
+	if len(loadbalancers) > 0 {
 		input.LoadBalancerNames = loadbalancers
 	}
 
-	if *target_group_arns[0] != "" {
+	if len(target_group_arns) > 0 {
 		input.TargetGroupARNs = target_group_arns
 	}
 
@@ -710,48 +766,71 @@ func (e EC2Client) GenerateTags(tagList []string, asg_name, app, stack, ansibleT
 }
 
 func (e EC2Client) GetAvailabilityZones(vpc string, azs []string) []string {
-	ret := []string{}
-	vpcId := e.GetVPCId(vpc)
-
-	input := &ec2.DescribeSubnetsInput{
-		Filters: []*ec2.Filter{
-			{
-				Name: aws.String("vpc-id"),
-				Values: []*string{
-					aws.String(vpcId),
-				},
-			},
-		},
+	ret, err := e.FindAvailabilityZones(e.GetVPCId(vpc), azs)
+	if err != nil {
+		tool.Exit(1)
 	}
 
-	result, err := e.Client.DescribeSubnets(input)
+	return ret
+}
+
+// FindAvailabilityZones returns availability zones of subnets in the VPC
+// If azs are specified, then only availability zones in azs are returned.
+func (e EC2Client) FindAvailabilityZones(vpcId string, azs []string) ([]string, error) {
+	subnets, err := e.describeSubnets(vpcId)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			default:
-				Logger.Errorln(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			Logger.Errorln(err.Error())
-		}
-		os.Exit(1)
+		return nil, err
 	}
 
-	for _, subnet := range result.Subnets {
+	ret := []string{}
+	for _, subnet := range subnets {
 		if tool.IsStringInArray(*subnet.AvailabilityZone, ret) || (len(azs) > 0 && !tool.IsStringInArray(*subnet.AvailabilityZone, azs)) {
 			continue
 		}
 		ret = append(ret, *subnet.AvailabilityZone)
 	}
 
-	return ret
+	return ret, nil
 }
 
 func (e EC2Client) GetSubnets(vpc string, use_public_subnets bool, azs []string) []string {
-	vpcId := e.GetVPCId(vpc)
+	ret, err := e.FindSubnets(e.GetVPCId(vpc), use_public_subnets, azs)
+	if err != nil {
+		tool.Exit(1)
+	}
 
+	return ret
+}
+
+// FindSubnets returns subnets in the availability zones which are tagged as public or private
+func (e EC2Client) FindSubnets(vpcId string, use_public_subnets bool, azs []string) ([]string, error) {
+	subnets, err := e.describeSubnets(vpcId)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []string{}
+	subnetType := "private"
+	if use_public_subnets {
+		subnetType = "public"
+	}
+	for _, subnet := range subnets {
+		if !tool.IsStringInArray(*subnet.AvailabilityZone, azs) {
+			continue
+		}
+
+		for _, tag := range subnet.Tags {
+			if *tag.Key == "Name" && strings.HasPrefix(*tag.Value, subnetType) {
+				ret = append(ret, *subnet.SubnetId)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// describeSubnets returns all subnets in the VPC
+func (e EC2Client) describeSubnets(vpcId string) ([]*ec2.Subnet, error) {
 	input := &ec2.DescribeSubnetsInput{
 		Filters: []*ec2.Filter{
 			{
@@ -775,27 +854,10 @@ func (e EC2Client) GetSubnets(vpc string, use_public_subnets bool, azs []string)
 			// Message from an error.
 			Logger.Errorln(err.Error())
 		}
-		os.Exit(1)
-	}
-
-	ret := []string{}
-	subnetType := "private"
-	if use_public_subnets {
-		subnetType = "public"
-	}
-	for _, subnet := range result.Subnets {
-		if !tool.IsStringInArray(*subnet.AvailabilityZone, azs) {
-			continue
-		}
-
-		for _, tag := range subnet.Tags {
-			if *tag.Key == "Name" && strings.HasPrefix(*tag.Value, subnetType) {
-				ret = append(ret, *subnet.SubnetId)
-			}
-		}
+		return nil, err
 	}
 
-	return ret
+	return result.Subnets, nil
 }
 
 // Update Autoscaling Group size
@@ -834,11 +896,31 @@ func (e EC2Client) UpdateAutoScalingGroup(asg string, min, max, desired int64) e
 //CreateScalingPolicy creates scaling policy
 func (e EC2Client) CreateScalingPolicy(policy builder.ScalePolicy, asg_name string) (*string, error) {
 	input := &autoscaling.PutScalingPolicyInput{
-		AdjustmentType:       aws.String(policy.AdjustmentType),
 		AutoScalingGroupName: aws.String(asg_name),
 		PolicyName:           aws.String(policy.Name),
-		ScalingAdjustment:    aws.Int64(policy.ScalingAdjustment),
-		Cooldown:             aws.Int64(policy.Cooldown),
+		PolicyType:           aws.String(builder.GetPolicyType(policy)),
+	}
+
+	if policy.EstimatedInstanceWarmup > 0 {
+		input.EstimatedInstanceWarmup = aws.Int64(policy.EstimatedInstanceWarmup)
+	}
+
+	switch builder.GetPolicyType(policy) {
+	case builder.SIMPLE_SCALING:
+		input.AdjustmentType = aws.String(policy.AdjustmentType)
+		input.ScalingAdjustment = aws.Int64(policy.ScalingAdjustment)
+		input.Cooldown = aws.Int64(policy.Cooldown)
+	case builder.STEP_SCALING:
+		input.AdjustmentType = aws.String(policy.AdjustmentType)
+		input.StepAdjustments = makeStepAdjustments(policy.StepAdjustments)
+		if len(policy.MetricAggregationType) > 0 {
+			input.MetricAggregationType = aws.String(policy.MetricAggregationType)
+		}
+		if policy.MinAdjustmentMagnitude > 0 {
+			input.MinAdjustmentMagnitude = aws.Int64(policy.MinAdjustmentMagnitude)
+		}
+	case builder.TARGET_TRACKING_SCALING:
+		input.TargetTrackingConfiguration = makeTargetTrackingConfiguration(policy.TargetTracking)
 	}
 
 	result, err := e.AsClient.PutScalingPolicy(input)
@@ -865,6 +947,236 @@ func (e EC2Client) CreateScalingPolicy(policy builder.ScalePolicy, asg_name stri
 	return result.PolicyARN, nil
 }
 
+// CreateScheduledAction creates scheduled action of autoscaling group
+func (e EC2Client) CreateScheduledAction(action builder.ScheduledAction, asg_name string) error {
+	input := &autoscaling.PutScheduledUpdateGroupActionInput{
+		AutoScalingGroupName: aws.String(asg_name),
+		ScheduledActionName:  aws.String(action.Name),
+		MinSize:              action.Min,
+		MaxSize:              action.Max,
+		DesiredCapacity:      action.Desired,
+	}
+
+	if len(action.Recurrence) > 0 {
+		input.Recurrence = aws.String(action.Recurrence)
+	}
+
+	if len(action.TimeZone) > 0 {
+		input.TimeZone = aws.String(action.TimeZone)
+	}
+
+	if len(action.StartTime) > 0 {
+		t, err := time.Parse(time.RFC3339, action.StartTime)
+		if err != nil {
+			return err
+		}
+		input.StartTime = aws.Time(t)
+	}
+
+	if len(action.EndTime) > 0 {
+		t, err := time.Parse(time.RFC3339, action.EndTime)
+		if err != nil {
+			return err
+		}
+		input.EndTime = aws.Time(t)
+	}
+
+	if _, err := e.AsClient.PutScheduledUpdateGroupAction(input); err != nil {
+		return err
+	}
+
+	Logger.Info(fmt.Sprintf("New scheduled action is created : %s / asg : %s", action.Name, asg_name))
+
+	return nil
+}
+
+// PutWarmPool creates warm pool of autoscaling group
+func (e EC2Client) PutWarmPool(asg_name string, warmPool builder.WarmPool) error {
+	input := &autoscaling.PutWarmPoolInput{
+		AutoScalingGroupName: aws.String(asg_name),
+		PoolState:            aws.String(builder.GetWarmPoolState(warmPool)),
+		MinSize:              aws.Int64(warmPool.MinSize),
+		InstanceReusePolicy: &autoscaling.InstanceReusePolicy{
+			ReuseOnScaleIn: aws.Bool(warmPool.ReuseOnScaleIn),
+		},
+	}
+
+	if warmPool.MaxPreparedCapacity > 0 {
+		input.MaxGroupPreparedCapacity = aws.Int64(warmPool.MaxPreparedCapacity)
+	}
+
+	if _, err := e.AsClient.PutWarmPool(input); err != nil {
+		return err
+	}
+
+	Logger.Info(fmt.Sprintf("Warm pool is created : %s", asg_name))
+
+	return nil
+}
+
+// DeleteWarmPool deletes warm pool of autoscaling group with instances in it
+func (e EC2Client) DeleteWarmPool(asg_name string) error {
+	input := &autoscaling.DeleteWarmPoolInput{
+		AutoScalingGroupName: aws.String(asg_name),
+		ForceDelete:          aws.Bool(true),
+	}
+
+	if _, err := e.AsClient.DeleteWarmPool(input); err != nil {
+		return err
+	}
+
+	Logger.Info(fmt.Sprintf("Warm pool is deleted : %s", asg_name))
+
+	return nil
+}
+
+// SuspendProcesses suspends processes of autoscaling group
+func (e EC2Client) SuspendProcesses(asg_name string, processes []string) error {
+	input := &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: aws.String(asg_name),
+		ScalingProcesses:     MakeStringArrayToAwsStrings(processes),
+	}
+
+	if _, err := e.AsClient.SuspendProcesses(input); err != nil {
+		return err
+	}
+
+	Logger.Info(fmt.Sprintf("Processes are suspended : %s / asg : %s", strings.Join(processes, ", "), asg_name))
+
+	return nil
+}
+
+// ResumeProcesses resumes suspended processes of autoscaling group
+func (e EC2Client) ResumeProcesses(asg_name string, processes []string) error {
+	input := &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: aws.String(asg_name),
+		ScalingProcesses:     MakeStringArrayToAwsStrings(processes),
+	}
+
+	if _, err := e.AsClient.ResumeProcesses(input); err != nil {
+		return err
+	}
+
+	Logger.Info(fmt.Sprintf("Processes are resumed : %s / asg : %s", strings.Join(processes, ", "), asg_name))
+
+	return nil
+}
+
+// DetachLoadBalancers detaches classic load balancers and target groups from autoscaling group
+// Instances are deregistered after deregistration delay or connection draining.
+func (e EC2Client) DetachLoadBalancers(asg_name string, load_balancers []*string, target_group_arns []*string) error {
+	if len(load_balancers) > 0 {
+		input := &autoscaling.DetachLoadBalancersInput{
+			AutoScalingGroupName: aws.String(asg_name),
+			LoadBalancerNames:    load_balancers,
+		}
+
+		if _, err := e.AsClient.DetachLoadBalancers(input); err != nil {
+			return err
+		}
+	}
+
+	if len(target_group_arns) > 0 {
+		input := &autoscaling.DetachLoadBalancerTargetGroupsInput{
+			AutoScalingGroupName: aws.String(asg_name),
+			TargetGroupARNs:      target_group_arns,
+		}
+
+		if _, err := e.AsClient.DetachLoadBalancerTargetGroups(input); err != nil {
+			return err
+		}
+	}
+
+	Logger.Info(fmt.Sprintf("Load balancers are detached : %s", asg_name))
+
+	return nil
+}
+
+// GetDetachingLoadBalancers returns load balancers and target groups which are not removed from autoscaling group yet
+func (e EC2Client) GetDetachingLoadBalancers(asg_name string) ([]string, error) {
+	ret := []string{}
+
+	lbs, err := e.AsClient.DescribeLoadBalancers(&autoscaling.DescribeLoadBalancersInput{
+		AutoScalingGroupName: aws.String(asg_name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lb := range lbs.LoadBalancers {
+		if *lb.State != "Removed" {
+			ret = append(ret, *lb.LoadBalancerName)
+		}
+	}
+
+	tgs, err := e.AsClient.DescribeLoadBalancerTargetGroups(&autoscaling.DescribeLoadBalancerTargetGroupsInput{
+		AutoScalingGroupName: aws.String(asg_name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tg := range tgs.LoadBalancerTargetGroups {
+		if *tg.State != "Removed" {
+			ret = append(ret, *tg.LoadBalancerTargetGroupARN)
+		}
+	}
+
+	return ret, nil
+}
+
+// makeStepAdjustments converts step adjustments to the ones of autoscaling
+func makeStepAdjustments(steps []builder.StepAdjustment) []*autoscaling.StepAdjustment {
+	ret := []*autoscaling.StepAdjustment{}
+	for _, step := range steps {
+		ret = append(ret, &autoscaling.StepAdjustment{
+			MetricIntervalLowerBound: step.LowerBound,
+			MetricIntervalUpperBound: step.UpperBound,
+			ScalingAdjustment:        aws.Int64(step.ScalingAdjustment),
+		})
+	}
+	return ret
+}
+
+// makeTargetTrackingConfiguration converts target tracking configuration to the one of autoscaling
+func makeTargetTrackingConfiguration(t builder.TargetTrackingConfiguration) *autoscaling.TargetTrackingConfiguration {
+	ret := &autoscaling.TargetTrackingConfiguration{
+		TargetValue:    aws.Float64(t.TargetValue),
+		DisableScaleIn: aws.Bool(t.DisableScaleIn),
+	}
+
+	if len(t.PredefinedMetric) > 0 {
+		ret.PredefinedMetricSpecification = &autoscaling.PredefinedMetricSpecification{
+			PredefinedMetricType: aws.String(t.PredefinedMetric),
+		}
+		if len(t.ResourceLabel) > 0 {
+			ret.PredefinedMetricSpecification.ResourceLabel = aws.String(t.ResourceLabel)
+		}
+		return ret
+	}
+
+	dimensions := []*autoscaling.MetricDimension{}
+	for _, dimension := range t.CustomizedMetric.Dimensions {
+		kv := strings.SplitN(dimension, "=", 2)
+		dimensions = append(dimensions, &autoscaling.MetricDimension{
+			Name:  aws.String(kv[0]),
+			Value: aws.String(kv[1]),
+		})
+	}
+
+	ret.CustomizedMetricSpecification = &autoscaling.CustomizedMetricSpecification{
+		Namespace:  aws.String(t.CustomizedMetric.Namespace),
+		MetricName: aws.String(t.CustomizedMetric.Metric),
+		Statistic:  aws.String(t.CustomizedMetric.Statistic),
+		Dimensions: dimensions,
+	}
+	if len(t.CustomizedMetric.Unit) > 0 {
+		ret.CustomizedMetricSpecification.Unit = aws.String(t.CustomizedMetric.Unit)
+	}
+
+	return ret
+}
+
 // EnableMetrics enables metric monitoring of autoscaling group
 func (e EC2Client) EnableMetrics(asg_name string) error {
 	input := &autoscaling.EnableMetricsCollectionInput{
@@ -915,6 +1227,36 @@ func (e EC2Client) GenerateLifecycleHooks(hooks builder.LifecycleHooks) []*autos
 	return ret
 }
 
+// CompleteLifecycleAction completes the lifecycle action of the instance with the result
+func (e EC2Client) CompleteLifecycleAction(asg_name, hook_name, instance_id, result string) error {
+	input := &autoscaling.CompleteLifecycleActionInput{
+		AutoScalingGroupName:  aws.String(asg_name),
+		LifecycleHookName:     aws.String(hook_name),
+		InstanceId:            aws.String(instance_id),
+		LifecycleActionResult: aws.String(result),
+	}
+
+	if _, err := e.AsClient.CompleteLifecycleAction(input); err != nil {
+		return err
+	}
+
+	Logger.Info(fmt.Sprintf("Lifecycle action is completed with %s : %s / %s", result, hook_name, instance_id))
+
+	return nil
+}
+
+// RecordLifecycleActionHeartbeat extends timeout of the lifecycle action of the instance
+func (e EC2Client) RecordLifecycleActionHeartbeat(asg_name, hook_name, instance_id string) error {
+	input := &autoscaling.RecordLifecycleActionHeartbeatInput{
+		AutoScalingGroupName: aws.String(asg_name),
+		LifecycleHookName:    aws.String(hook_name),
+		InstanceId:           aws.String(instance_id),
+	}
+
+	_, err := e.AsClient.RecordLifecycleActionHeartbeat(input)
+	return err
+}
+
 func createSingleLifecycleHookSpecification(l builder.LifecycleHookSpecification, transition string) autoscaling.LifecycleHookSpecification {
 	lhs := autoscaling.LifecycleHookSpecification{
 		LifecycleHookName:   aws.String(l.LifecycleHookName),
@@ -943,3 +1285,325 @@ func createSingleLifecycleHookSpecification(l builder.LifecycleHookSpecification
 
 	return lhs
 }
+
+// CheckKeyPair checks if the key pair exists
+func (e EC2Client) CheckKeyPair(keyName string) error {
+	input := &ec2.DescribeKeyPairsInput{
+		KeyNames: []*string{aws.String(keyName)},
+	}
+
+	result, err := e.Client.DescribeKeyPairs(input)
+	if err != nil {
+		return err
+	}
+
+	if len(result.KeyPairs) == 0 {
+		return fmt.Errorf("key pair does not exist : %s", keyName)
+	}
+
+	return nil
+}
+
+// GetImage returns the information of AMI
+func (e EC2Client) GetImage(ami string) (*ec2.Image, error) {
+	input := &ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String(ami)},
+	}
+
+	result, err := e.Client.DescribeImages(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Images) == 0 {
+		return nil, fmt.Errorf("AMI does not exist : %s", ami)
+	}
+
+	return result.Images[0], nil
+}
+
+// GetPrivateIpAddresses returns private ip addresses of instances
+// Instances which don't have private ip address yet are not included.
+func (e EC2Client) GetPrivateIpAddresses(instanceIds []string) (map[string]string, error) {
+	ret := map[string]string{}
+	if len(instanceIds) == 0 {
+		return ret, nil
+	}
+
+	input := &ec2.DescribeInstancesInput{
+		InstanceIds: MakeStringArrayToAwsStrings(instanceIds),
+	}
+
+	err := e.Client.DescribeInstancesPages(input, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.PrivateIpAddress != nil {
+					ret[*instance.InstanceId] = *instance.PrivateIpAddress
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetSupportedArchitectures returns architectures which the instance type supports
+func (e EC2Client) GetSupportedArchitectures(instanceType string) ([]string, error) {
+	input := &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []*string{aws.String(instanceType)},
+	}
+
+	result, err := e.Client.DescribeInstanceTypes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.InstanceTypes) == 0 || result.InstanceTypes[0].ProcessorInfo == nil {
+		return nil, fmt.Errorf("instance type does not exist : %s", instanceType)
+	}
+
+	return aws.StringValueSlice(result.InstanceTypes[0].ProcessorInfo.SupportedArchitectures), nil
+}
+
+// GetOfferedAvailabilityZones returns availability zones where the instance type is offered
+func (e EC2Client) GetOfferedAvailabilityZones(instanceType string) ([]string, error) {
+	input := &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(ec2.LocationTypeAvailabilityZone),
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-type"),
+				Values: []*string{aws.String(instanceType)},
+			},
+		},
+	}
+
+	ret := []string{}
+	err := e.Client.DescribeInstanceTypeOfferingsPages(input, func(page *ec2.DescribeInstanceTypeOfferingsOutput, lastPage bool) bool {
+		for _, offering := range page.InstanceTypeOfferings {
+			ret = append(ret, *offering.Location)
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// FindLatestImage returns the latest available AMI which matches the name pattern and tags
+func (e EC2Client) FindLatestImage(name string, owners, tags []string) (string, error) {
+	filters := []*ec2.Filter{
+		{
+			Name:   aws.String("state"),
+			Values: []*string{aws.String("available")},
+		},
+	}
+
+	if len(name) > 0 {
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String("name"),
+			Values: []*string{aws.String(name)},
+		})
+	}
+
+	for _, tag := range tags {
+		kv := strings.SplitN(tag, "=", 2)
+		filters = append(filters, &ec2.Filter{
+			Name:   aws.String(fmt.Sprintf("tag:%s", kv[0])),
+			Values: []*string{aws.String(kv[1])},
+		})
+	}
+
+	if len(owners) == 0 {
+		owners = []string{"self"}
+	}
+
+	input := &ec2.DescribeImagesInput{
+		Owners:  MakeStringArrayToAwsStrings(owners),
+		Filters: filters,
+	}
+
+	result, err := e.Client.DescribeImages(input)
+	if err != nil {
+		return "", err
+	}
+
+	if len(result.Images) == 0 {
+		return "", fmt.Errorf("no AMI matches name %q and tags %v", name, tags)
+	}
+
+	latest := result.Images[0]
+	for _, image := range result.Images {
+		// CreationDate is ISO 8601 format so that it can be compared as string
+		if *image.CreationDate > *latest.CreationDate {
+			latest = image
+		}
+	}
+
+	Logger.Infof("AMI is selected : %s(%s)", *latest.ImageId, aws.StringValue(latest.Name))
+
+	return *latest.ImageId, nil
+}
+
+// CopyImage copies the AMI from the source region and waits until the copied AMI is available
+// If the AMI was already copied with the same KMS key by previous deployment, then the copied one is reused.
+// The copy is named after the source AMI, its id and the KMS key so that copies with different keys do not collide.
+// Waiting stops when ctx is done.
+func (e EC2Client) CopyImage(ctx aws.Context, image *ec2.Image, sourceRegion, kmsKeyId string) (string, error) {
+	input := &ec2.DescribeImagesInput{
+		Owners: []*string{aws.String("self")},
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", AMI_COPY_SOURCE_TAG)),
+				Values: []*string{image.ImageId},
+			},
+		},
+	}
+
+	result, err := e.Client.DescribeImages(input)
+	if err != nil {
+		return "", err
+	}
+
+	var copied *string
+	for _, i := range result.Images {
+		// Copies without the tag are not encrypted by goployer
+		if getImageTag(i, AMI_COPY_KMS_KEY_TAG) != kmsKeyId {
+			continue
+		}
+
+		if *i.State == ec2.ImageStateAvailable || *i.State == ec2.ImageStatePending {
+			copied = i.ImageId
+			Logger.Infof("AMI was already copied from %s : %s", *image.ImageId, *copied)
+			break
+		}
+	}
+
+	name := copiedImageName(image, kmsKeyId)
+	tagged := copied != nil
+	if copied == nil {
+		// Copy could be left without tags when previous attempt failed before tagging
+		copied, err = e.findImageByName(name)
+		if err != nil {
+			return "", err
+		}
+		if copied != nil {
+			Logger.Infof("AMI was already copied with name %s : %s", name, *copied)
+		}
+	}
+
+	if copied == nil {
+		copyInput := &ec2.CopyImageInput{
+			Name:          aws.String(name),
+			Description:   aws.String(fmt.Sprintf("Copied from %s in %s", *image.ImageId, sourceRegion)),
+			SourceImageId: image.ImageId,
+			SourceRegion:  aws.String(sourceRegion),
+		}
+
+		if len(kmsKeyId) > 0 {
+			copyInput.Encrypted = aws.Bool(true)
+			copyInput.KmsKeyId = aws.String(kmsKeyId)
+		}
+
+		ret, err := e.Client.CopyImage(copyInput)
+		if err != nil {
+			return "", err
+		}
+		copied = ret.ImageId
+		Logger.Infof("Start copying AMI from %s(%s) : %s", *image.ImageId, sourceRegion, *copied)
+	}
+
+	if !tagged {
+		// Tags are set before waiting so that next deployment could find the copy
+		tags := []*ec2.Tag{
+			{Key: aws.String(AMI_COPY_SOURCE_TAG), Value: image.ImageId},
+			{Key: aws.String(AMI_COPY_SOURCE_REGION_TAG), Value: aws.String(sourceRegion)},
+		}
+		if len(kmsKeyId) > 0 {
+			tags = append(tags, &ec2.Tag{Key: aws.String(AMI_COPY_KMS_KEY_TAG), Value: aws.String(kmsKeyId)})
+		}
+		for _, tag := range image.Tags {
+			if strings.HasPrefix(*tag.Key, "aws:") || *tag.Key == AMI_COPY_KMS_KEY_TAG {
+				continue
+			}
+			tags = append(tags, tag)
+		}
+
+		_, err = e.Client.CreateTags(&ec2.CreateTagsInput{
+			Resources: []*string{copied},
+			Tags:      tags,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	Logger.Infof("Waiting for AMI to be available : %s", *copied)
+	err = e.Client.WaitUntilImageAvailableWithContext(
+		ctx,
+		&ec2.DescribeImagesInput{ImageIds: []*string{copied}},
+		request.WithWaiterMaxAttempts(AMI_COPY_WAITER_MAX_ATTEMPTS),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return *copied, nil
+}
+
+// findImageByName returns the id of available or pending AMI with the name owned by this account
+func (e EC2Client) findImageByName(name string) (*string, error) {
+	result, err := e.Client.DescribeImages(&ec2.DescribeImagesInput{
+		Owners: []*string{aws.String("self")},
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("name"),
+				Values: []*string{aws.String(name)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range result.Images {
+		if *i.State == ec2.ImageStateAvailable || *i.State == ec2.ImageStatePending {
+			return i.ImageId, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// copiedImageName returns the name of the copy which is unique per source AMI and KMS key
+// AMI name should be unique in the account and region, and at most 128 characters.
+func copiedImageName(image *ec2.Image, kmsKeyId string) string {
+	suffix := fmt.Sprintf("-%s", *image.ImageId)
+	if len(kmsKeyId) > 0 {
+		// Key ARN has characters which are not allowed in AMI name
+		sum := sha256.Sum256([]byte(kmsKeyId))
+		suffix = fmt.Sprintf("%s-%s", suffix, hex.EncodeToString(sum[:])[:8])
+	}
+
+	name := aws.StringValue(image.Name)
+	if len(name)+len(suffix) > AMI_NAME_MAX_LENGTH {
+		name = name[:AMI_NAME_MAX_LENGTH-len(suffix)]
+	}
+
+	return name + suffix
+}
+
+// getImageTag returns the value of the tag in the image
+func getImageTag(image *ec2.Image, key string) string {
+	for _, tag := range image.Tags {
+		if *tag.Key == key {
+			return *tag.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/elb"
+	Logger "github.com/sirupsen/logrus"
+)
+
+type ELBClient struct {
+	Client *elb.ELB
+}
+
+func NewELBClient(session *session.Session, region string, creds *credentials.Credentials) ELBClient {
+	return ELBClient{
+		Client: getClassicElbClientFn(session, region, creds),
+	}
+}
+
+func getClassicElbClientFn(session *session.Session, region string, creds *credentials.Credentials) *elb.ELB {
+	if creds == nil {
+		return elb.New(session, &aws.Config{Region: aws.String(region)})
+	}
+	return elb.New(session, &aws.Config{Region: aws.String(region), Credentials: creds})
+}
+
+// GetHostInLoadBalancer gets health of instances in the classic load balancer
+func (e ELBClient) GetHostInLoadBalancer(group *autoscaling.Group, load_balancer string) ([]HealthcheckHost, error) {
+	Logger.Debug(fmt.Sprintf("[Checking healthy host count] Autoscaling Group: %s / Load balancer: %s", *group.AutoScalingGroupName, load_balancer))
+
+	input := &elb.DescribeInstanceHealthInput{
+		LoadBalancerName: aws.String(load_balancer),
+	}
+
+	result, err := e.Client.DescribeInstanceHealth(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case elb.ErrCodeAccessPointNotFoundException:
+				Logger.Errorln(elb.ErrCodeAccessPointNotFoundException, aerr.Error())
+			case elb.ErrCodeInvalidEndPointException:
+				Logger.Errorln(elb.ErrCodeInvalidEndPointException, aerr.Error())
+			default:
+				Logger.Errorln(aerr.Error())
+			}
+		} else {
+			// Print the error, cast err to awserr.Error to get the Code and
+			// Message from an error.
+			Logger.Errorln(err.Error())
+		}
+		return nil, err
+	}
+
+	ret := []HealthcheckHost{}
+	for _, instance := range group.Instances {
+		state := tool.INITIAL_STATUS
+		for _, is := range result.InstanceStates {
+			if *is.InstanceId == *instance.InstanceId {
+				state = *is.State
+				break
+			}
+		}
+
+		ret = append(ret, HealthcheckHost{
+			InstanceId:     *instance.InstanceId,
+			LifecycleState: *instance.LifecycleState,
+			TargetStatus:   state,
+			HealthStatus:   *instance.HealthStatus,
+			Healthy:        *instance.LifecycleState == "InService" && state == "InService" && *instance.HealthStatus == "Healthy",
+		})
+	}
+
+	return ret, nil
+}
+
+// GetConnectionDrainingTimeout returns timeout of connection draining of the classic load balancer in seconds
+// If connection draining is disabled, then 0 is returned.
+func (e ELBClient) GetConnectionDrainingTimeout(load_balancer string) (int64, error) {
+	input := &elb.DescribeLoadBalancerAttributesInput{
+		LoadBalancerName: aws.String(load_balancer),
+	}
+
+	result, err := e.Client.DescribeLoadBalancerAttributes(input)
+	if err != nil {
+		return 0, err
+	}
+
+	draining := result.LoadBalancerAttributes.ConnectionDraining
+	if draining == nil || !*draining.Enabled {
+		return 0, nil
+	}
+
+	return *draining.Timeout, nil
+}
+
+// CheckLoadBalancer checks if the classic load balancer exists
+func (e ELBClient) CheckLoadBalancer(load_balancer string) error {
+	input := &elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: []*string{aws.String(load_balancer)},
+	}
+
+	result, err := e.Client.DescribeLoadBalancers(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == elb.ErrCodeAccessPointNotFoundException {
+			return fmt.Errorf("classic load balancer does not exist : %s", load_balancer)
+		}
+		return err
+	}
+
+	if len(result.LoadBalancerDescriptions) == 0 {
+		return fmt.Errorf("classic load balancer does not exist : %s", load_balancer)
+	}
+
+	return nil
+}
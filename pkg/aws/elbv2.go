@@ -10,7 +10,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/elbv2"
 	Logger "github.com/sirupsen/logrus"
-	"os"
+	"strconv"
+	"strings"
 )
 
 type ELBV2Client struct {
@@ -64,7 +65,7 @@ func (e ELBV2Client) GetTargetGroupARNs(target_groups []string) []*string {
 			// Message from an error.
 			Logger.Errorln(err.Error())
 		}
-		os.Exit(1)
+		tool.Exit(1)
 	}
 
 	ret := []*string{}
@@ -76,7 +77,7 @@ func (e ELBV2Client) GetTargetGroupARNs(target_groups []string) []*string {
 }
 
 // GetHostInTarget gets host instance
-func (e ELBV2Client) GetHostInTarget(group *autoscaling.Group, target_group_arn *string) []HealthcheckHost {
+func (e ELBV2Client) GetHostInTarget(group *autoscaling.Group, target_group_arn *string) ([]HealthcheckHost, error) {
 	Logger.Debug(fmt.Sprintf("[Checking healthy host count] Autoscaling Group: %s", *group.AutoScalingGroupName))
 
 	input := &elbv2.DescribeTargetHealthInput{
@@ -101,9 +102,11 @@ func (e ELBV2Client) GetHostInTarget(group *autoscaling.Group, target_group_arn
 			// Message from an error.
 			Logger.Errorln(err.Error())
 		}
-		os.Exit(1)
+		return nil, err
 	}
 
+	// Instances in warm pool are not counted because they are not listed in group.Instances.
+	// They are only returned by DescribeWarmPool until they are launched into the group.
 	ret := []HealthcheckHost{}
 	for _, instance := range group.Instances {
 		target_state := tool.INITIAL_STATUS
@@ -122,5 +125,94 @@ func (e ELBV2Client) GetHostInTarget(group *autoscaling.Group, target_group_arn
 			Healthy:        *instance.LifecycleState == "InService" && target_state == "healthy" && *instance.HealthStatus == "Healthy",
 		})
 	}
-	return ret
+	return ret, nil
+}
+
+// FindTargetGroupARN returns arn of the target group
+func (e ELBV2Client) FindTargetGroupARN(target_group string) (string, error) {
+	input := &elbv2.DescribeTargetGroupsInput{
+		Names: []*string{aws.String(target_group)},
+	}
+
+	result, err := e.Client.DescribeTargetGroups(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == elbv2.ErrCodeTargetGroupNotFoundException {
+			return "", fmt.Errorf("target group does not exist : %s", target_group)
+		}
+		return "", err
+	}
+
+	if len(result.TargetGroups) == 0 {
+		return "", fmt.Errorf("target group does not exist : %s", target_group)
+	}
+
+	return *result.TargetGroups[0].TargetGroupArn, nil
+}
+
+// GetResourceLabel returns resource label of the target group for ALBRequestCountPerTarget metric
+// Resource label is like app/<load-balancer-name>/<id>/targetgroup/<target-group-name>/<id>.
+func (e ELBV2Client) GetResourceLabel(target_group string) (string, error) {
+	input := &elbv2.DescribeTargetGroupsInput{
+		Names: []*string{aws.String(target_group)},
+	}
+
+	result, err := e.Client.DescribeTargetGroups(input)
+	if err != nil {
+		return "", err
+	}
+
+	if len(result.TargetGroups) == 0 {
+		return "", fmt.Errorf("target group does not exist : %s", target_group)
+	}
+
+	tg := result.TargetGroups[0]
+	if len(tg.LoadBalancerArns) == 0 {
+		return "", fmt.Errorf("target group is not attached to any load balancer : %s", target_group)
+	}
+
+	lbArn := *tg.LoadBalancerArns[0]
+	tgArn := *tg.TargetGroupArn
+
+	return fmt.Sprintf("%s/%s", lbArn[strings.Index(lbArn, "loadbalancer/")+len("loadbalancer/"):], tgArn[strings.LastIndex(tgArn, ":")+1:]), nil
+}
+
+// GetDeregistrationDelay returns deregistration delay of the target group in seconds
+func (e ELBV2Client) GetDeregistrationDelay(target_group_arn string) (int64, error) {
+	input := &elbv2.DescribeTargetGroupAttributesInput{
+		TargetGroupArn: aws.String(target_group_arn),
+	}
+
+	result, err := e.Client.DescribeTargetGroupAttributes(input)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, attribute := range result.Attributes {
+		if *attribute.Key == "deregistration_delay.timeout_seconds" {
+			return strconv.ParseInt(*attribute.Value, 10, 64)
+		}
+	}
+
+	return 0, nil
+}
+
+// GetDrainingTargetCount returns the number of instances which are draining in the target group
+func (e ELBV2Client) GetDrainingTargetCount(target_group_arn string, instance_ids []string) (int, error) {
+	input := &elbv2.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(target_group_arn),
+	}
+
+	result, err := e.Client.DescribeTargetHealth(input)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, hd := range result.TargetHealthDescriptions {
+		if tool.IsStringInArray(*hd.Target.Id, instance_ids) && *hd.TargetHealth.State == elbv2.TargetHealthStateEnumDraining {
+			count++
+		}
+	}
+
+	return count, nil
 }
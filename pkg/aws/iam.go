@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+type IAMClient struct {
+	Client *iam.IAM
+}
+
+func NewIAMClient(session *session.Session, region string, creds *credentials.Credentials) IAMClient {
+	return IAMClient{
+		Client: getIAMClientFn(session, region, creds),
+	}
+}
+
+func getIAMClientFn(session *session.Session, region string, creds *credentials.Credentials) *iam.IAM {
+	if creds == nil {
+		return iam.New(session, &aws.Config{Region: aws.String(region)})
+	}
+	return iam.New(session, &aws.Config{Region: aws.String(region), Credentials: creds})
+}
+
+// CheckInstanceProfile checks if the instance profile exists
+func (i IAMClient) CheckInstanceProfile(name string) error {
+	input := &iam.GetInstanceProfileInput{
+		InstanceProfileName: aws.String(name),
+	}
+
+	_, err := i.Client.GetInstanceProfile(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			return fmt.Errorf("instance profile does not exist : %s", name)
+		}
+		return err
+	}
+
+	return nil
+}
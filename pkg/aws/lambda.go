@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	Logger "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type LambdaClient struct {
+	Client *lambda.Lambda
+}
+
+func NewLambdaClient(session *session.Session, region string, creds *credentials.Credentials) LambdaClient {
+	return LambdaClient{
+		Client: getLambdaClientFn(session, region, creds),
+	}
+}
+
+func getLambdaClientFn(session *session.Session, region string, creds *credentials.Credentials) *lambda.Lambda {
+	if creds == nil {
+		return lambda.New(session, &aws.Config{Region: aws.String(region)})
+	}
+	return lambda.New(session, &aws.Config{Region: aws.String(region), Credentials: creds})
+}
+
+// InvokeFunction invokes the function synchronously with the payload
+// It returns the response payload, and error if the function fails.
+func (l LambdaClient) InvokeFunction(name, qualifier string, payload []byte) ([]byte, error) {
+	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(name),
+		InvocationType: aws.String(lambda.InvocationTypeRequestResponse),
+		LogType:        aws.String(lambda.LogTypeTail),
+		Payload:        payload,
+	}
+
+	if len(qualifier) > 0 {
+		input.Qualifier = aws.String(qualifier)
+	}
+
+	result, err := l.Client.Invoke(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.LogResult != nil {
+		if logs, err := base64.StdEncoding.DecodeString(*result.LogResult); err == nil {
+			Logger.Debugf("logs of function %s :\n%s", name, string(logs))
+		}
+	}
+
+	if result.FunctionError != nil {
+		return result.Payload, fmt.Errorf("function %s failed with %s : %s", name, *result.FunctionError, string(result.Payload))
+	}
+
+	if *result.StatusCode != http.StatusOK {
+		return result.Payload, fmt.Errorf("function %s returned status %d : %s", name, *result.StatusCode, string(result.Payload))
+	}
+
+	return result.Payload, nil
+}
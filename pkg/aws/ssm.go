@@ -1,12 +1,17 @@
 package aws
 
 import (
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type SSMClient struct {
@@ -26,19 +31,34 @@ func getSsmClientFn(session *session.Session, region string, creds *credentials.
 	return ssm.New(session, &aws.Config{Region: aws.String(region), Credentials: creds})
 }
 
-//SSM Send command
-func (s SSMClient) SendCommand(target []*string, commands []*string) bool {
+var (
+	SSM_COMMAND_PENDING_STATUS = []string{"Pending", "InProgress", "Delayed"}
+	SSM_COMMAND_WAIT_INTERVAL  = 3 * time.Second
+)
+
+type CommandInvocation struct {
+	InstanceId   string
+	Status       string
+	ResponseCode int64
+	Stdout       string
+	Stderr       string
+}
+
+// SendCommandWithComment sends shell commands with comment and timeout
+// The timeout bounds both the delivery of the command and the execution of the script in the instance.
+func (s SSMClient) SendCommandWithComment(target []*string, commands []*string, comment string, timeout int64) (string, error) {
 	input := &ssm.SendCommandInput{
 		DocumentName:   aws.String("AWS-RunShellScript"),
-		TimeoutSeconds: aws.Int64(3600),
+		TimeoutSeconds: aws.Int64(timeout),
 		InstanceIds:    target,
-		Comment:        aws.String("goployer lifecycle callbacks"),
+		Comment:        aws.String(comment),
 		Parameters: map[string][]*string{
-			"commands": commands,
+			"commands":         commands,
+			"executionTimeout": {aws.String(strconv.FormatInt(timeout, 10))},
 		},
 	}
 
-	_, err := s.Client.SendCommand(input)
+	result, err := s.Client.SendCommand(input)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
@@ -58,8 +78,115 @@ func (s SSMClient) SendCommand(target []*string, commands []*string) bool {
 			// Message from an error.
 			logrus.Errorln(err.Error())
 		}
-		return false
+		return "", err
+	}
+
+	return *result.Command.CommandId, nil
+}
+
+// GetCommandInvocation returns the result of the command in the instance
+// If the invocation is not created yet, then it is regarded as pending.
+func (s SSMClient) GetCommandInvocation(commandId, instanceId string) (CommandInvocation, error) {
+	input := &ssm.GetCommandInvocationInput{
+		CommandId:  aws.String(commandId),
+		InstanceId: aws.String(instanceId),
+	}
+
+	result, err := s.Client.GetCommandInvocation(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeInvocationDoesNotExist {
+			return CommandInvocation{InstanceId: instanceId, Status: "Pending", ResponseCode: -1}, nil
+		}
+		return CommandInvocation{}, err
+	}
+
+	return CommandInvocation{
+		InstanceId:   instanceId,
+		Status:       *result.Status,
+		ResponseCode: *result.ResponseCode,
+		Stdout:       *result.StandardOutputContent,
+		Stderr:       *result.StandardErrorContent,
+	}, nil
+}
+
+// WaitCommandInvocations waits for the results of commands per instance until timeout
+// Invocations which are not finished in time are returned as they are.
+// Transient errors like throttling are retried until timeout. Instances without any result are not returned.
+func (s SSMClient) WaitCommandInvocations(commandIds map[string]string, timeout int64) map[string]CommandInvocation {
+	pending := map[string]string{}
+	for instanceId, commandId := range commandIds {
+		pending[instanceId] = commandId
+	}
+
+	invocations := map[string]CommandInvocation{}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+
+	for len(pending) > 0 {
+		for instanceId, commandId := range pending {
+			invocation, err := s.GetCommandInvocation(commandId, instanceId)
+			if err != nil {
+				if request.IsErrorRetryable(err) || request.IsErrorThrottle(err) {
+					logrus.Warnf("[%s] failed to get command result, and it will be retried : %s", instanceId, err.Error())
+					continue
+				}
+
+				logrus.Warnf("[%s] failed to get command result : %s", instanceId, err.Error())
+				delete(pending, instanceId)
+				continue
+			}
+
+			invocations[instanceId] = invocation
+			if !IsCommandPending(invocation) {
+				delete(pending, instanceId)
+			}
+		}
+
+		if len(pending) == 0 || time.Now().After(deadline) {
+			break
+		}
+
+		time.Sleep(SSM_COMMAND_WAIT_INTERVAL)
+	}
+
+	return invocations
+}
+
+// IsCommandPending checks if the command is not finished in the instance
+func IsCommandPending(invocation CommandInvocation) bool {
+	return tool.IsStringInArray(invocation.Status, SSM_COMMAND_PENDING_STATUS)
+}
+
+// IsCommandSucceeded checks if the command exits with zero in the instance
+func IsCommandSucceeded(invocation CommandInvocation) bool {
+	return invocation.Status == ssm.CommandInvocationStatusSuccess && invocation.ResponseCode == 0
+}
+
+// LogCommandInvocation prints outputs of the command
+func LogCommandInvocation(name string, invocation CommandInvocation) {
+	if IsCommandPending(invocation) {
+		logrus.Infof("[%s] %s command is not finished : %s", invocation.InstanceId, name, invocation.Status)
+		return
+	}
+
+	logrus.Infof("[%s] %s command %s with exit code %d", invocation.InstanceId, name, invocation.Status, invocation.ResponseCode)
+	if len(invocation.Stdout) > 0 {
+		logrus.Infof("[%s] stdout : %s", invocation.InstanceId, strings.TrimSpace(invocation.Stdout))
+	}
+	if len(invocation.Stderr) > 0 {
+		logrus.Infof("[%s] stderr : %s", invocation.InstanceId, strings.TrimSpace(invocation.Stderr))
+	}
+}
+
+// GetParameter returns the value of SSM parameter
+func (s SSMClient) GetParameter(name string) (string, error) {
+	input := &ssm.GetParameterInput{
+		Name: aws.String(name),
+	}
+
+	result, err := s.Client.GetParameter(input)
+	if err != nil {
+		return "", err
 	}
 
-	return true
+	return *result.Parameter.Value, nil
 }
@@ -0,0 +1,149 @@
+package builder
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"regexp"
+	"strings"
+)
+
+var (
+	availableTreatMissingData = []string{"breaching", "notBreaching", "ignore", "missing"}
+	alarmMetricIdRegex        = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]*$`)
+)
+
+// AlarmMetric is a metric or an expression of metric math alarm
+type AlarmMetric struct {
+	Id         string   `yaml:"id" description:"ID of the metric, which expressions refer." required:"true"`
+	Expression string   `yaml:"expression" description:"Metric math expression. It cannot be used with namespace, metric and statistic."`
+	Label      string   `yaml:"label" description:"Label of the metric or expression."`
+	Namespace  string   `yaml:"namespace" description:"Namespace of the metric."`
+	Metric     string   `yaml:"metric" description:"Name of the metric."`
+	Statistic  string   `yaml:"statistic" description:"Statistic of the metric, e.g. 'Average' or 'Sum'."`
+	Period     int64    `yaml:"period" description:"Seconds of the period of the metric."`
+	Dimensions []string `yaml:"dimensions" description:"Dimensions of the metric in 'name=value' format."`
+	ReturnData bool     `yaml:"return_data" description:"Whether the alarm is evaluated with this metric. Exactly one metric should return data."`
+}
+
+// IsArn checks if the action is ARN, not a name of scaling policy
+func IsArn(action string) bool {
+	return strings.HasPrefix(action, "arn:")
+}
+
+// checkAlarms checks validation of cloudwatch alarms
+func checkAlarms(alarms []AlarmConfigs, policies []ScalePolicy) error {
+	policyNames := []string{}
+	for _, policy := range policies {
+		policyNames = append(policyNames, policy.Name)
+	}
+
+	names := []string{}
+	for _, alarm := range alarms {
+		if len(alarm.Name) == 0 {
+			return fmt.Errorf("alarm doesn't have a name.")
+		}
+
+		if tool.IsStringInArray(alarm.Name, names) {
+			return fmt.Errorf("names of alarms are duplicated : %s", alarm.Name)
+		}
+		names = append(names, alarm.Name)
+
+		actions := append(append(append([]string{}, alarm.AlarmActions...), alarm.OKActions...), alarm.InsufficientDataActions...)
+		for _, action := range actions {
+			if !IsArn(action) && !tool.IsStringInArray(action, policyNames) {
+				return fmt.Errorf("no scaling action exists : %s", action)
+			}
+		}
+
+		if len(alarm.TreatMissingData) > 0 && !tool.IsStringInArray(alarm.TreatMissingData, availableTreatMissingData) {
+			return fmt.Errorf("treat_missing_data should be one of %v : %s", availableTreatMissingData, alarm.Name)
+		}
+
+		if len(alarm.Scope) > 0 && !tool.IsStringInArray(alarm.Scope, availableMetricScopes) {
+			return fmt.Errorf("scope of alarm should be one of %v : %s", availableMetricScopes, alarm.Name)
+		}
+
+		if alarm.DatapointsToAlarm > alarm.EvaluationPeriods {
+			return fmt.Errorf("datapoints_to_alarm cannot be larger than evaluation_periods : %s", alarm.Name)
+		}
+
+		if err := checkDimensions(alarm.Dimensions); err != nil {
+			return err
+		}
+
+		if len(alarm.Metrics) > 0 {
+			if err := checkAlarmMetrics(alarm); err != nil {
+				return fmt.Errorf("%s : %s", err.Error(), alarm.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkAlarmMetrics checks metrics of metric math alarm
+func checkAlarmMetrics(alarm AlarmConfigs) error {
+	if len(alarm.Namespace) > 0 || len(alarm.Metric) > 0 || len(alarm.Statistic) > 0 || len(alarm.Dimensions) > 0 {
+		return fmt.Errorf("namespace, metric, statistic and dimensions should be set in metrics for metric math alarm")
+	}
+
+	ids := []string{}
+	returnData := 0
+	for _, m := range alarm.Metrics {
+		if !alarmMetricIdRegex.MatchString(m.Id) {
+			return fmt.Errorf("id of metric should start with a lowercase letter and contain only letters, numbers and underscore : %s", m.Id)
+		}
+
+		if tool.IsStringInArray(m.Id, ids) {
+			return fmt.Errorf("ids of metrics are duplicated : %s", m.Id)
+		}
+		ids = append(ids, m.Id)
+
+		if m.ReturnData {
+			returnData++
+		}
+
+		if len(m.Expression) > 0 {
+			if len(m.Namespace) > 0 || len(m.Metric) > 0 || len(m.Statistic) > 0 || len(m.Dimensions) > 0 {
+				return fmt.Errorf("expression cannot be used with namespace, metric, statistic and dimensions : %s", m.Id)
+			}
+			continue
+		}
+
+		if len(m.Namespace) == 0 || len(m.Metric) == 0 || len(m.Statistic) == 0 || m.Period == 0 {
+			return fmt.Errorf("namespace, metric, statistic and period are required for metric : %s", m.Id)
+		}
+
+		if err := checkDimensions(m.Dimensions); err != nil {
+			return err
+		}
+	}
+
+	if returnData != 1 {
+		return fmt.Errorf("exactly one of metrics should have return_data")
+	}
+
+	return nil
+}
+
+// checkDimensions checks format of dimensions
+func checkDimensions(dimensions []string) error {
+	for _, dimension := range dimensions {
+		if len(strings.Split(dimension, "=")) != 2 {
+			return fmt.Errorf("dimension should be like key=value : %s", dimension)
+		}
+	}
+
+	return nil
+}
+
+// checkAlarmTargets checks if the region has target group for alarms scoped to target group
+func checkAlarmTargets(alarms []AlarmConfigs, region RegionConfig) error {
+	for _, alarm := range alarms {
+		if alarm.Scope == METRIC_SCOPE_TARGET_GROUP && len(GetPrimaryTargetGroup(region)) == 0 {
+			return fmt.Errorf("no target group exists for alarm %s", alarm.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package builder
+
+import (
+	"testing"
+)
+
+func TestCheckAlarmMetrics(t *testing.T) {
+	// 5xx rate of the target group
+	requests := AlarmMetric{Id: "requests", Namespace: "AWS/ApplicationELB", Metric: "RequestCount", Statistic: "Sum", Period: 60}
+	errors := AlarmMetric{Id: "errors", Namespace: "AWS/ApplicationELB", Metric: "HTTPCode_Target_5XX_Count", Statistic: "Sum", Period: 60}
+	rate := AlarmMetric{Id: "error_rate", Expression: "errors / requests * 100", ReturnData: true}
+
+	tests := []struct {
+		name  string
+		alarm AlarmConfigs
+		err   string
+	}{
+		{name: "expression returns data", alarm: AlarmConfigs{Metrics: []AlarmMetric{requests, errors, rate}}},
+		{
+			name:  "metric returns data",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{{Id: "cpu", Namespace: "AWS/EC2", Metric: "CPUUtilization", Statistic: "Average", Period: 60, ReturnData: true}}},
+		},
+		{
+			name:  "metric of alarm is set",
+			alarm: AlarmConfigs{Metric: "CPUUtilization", Metrics: []AlarmMetric{requests, errors, rate}},
+			err:   "should be set in metrics",
+		},
+		{
+			name:  "no metric returns data",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{requests, errors, {Id: "error_rate", Expression: "errors / requests * 100"}}},
+			err:   "exactly one of metrics",
+		},
+		{
+			name: "two metrics return data",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{
+				requests,
+				{Id: "errors", Namespace: "AWS/ApplicationELB", Metric: "HTTPCode_Target_5XX_Count", Statistic: "Sum", Period: 60, ReturnData: true},
+				rate,
+			}},
+			err: "exactly one of metrics",
+		},
+		{
+			name:  "id starts with uppercase",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{{Id: "Rate", Expression: "1", ReturnData: true}}},
+			err:   "id of metric",
+		},
+		{
+			name:  "duplicated ids",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{requests, requests, rate}},
+			err:   "duplicated",
+		},
+		{
+			name:  "expression with metric",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{{Id: "rate", Expression: "1", Metric: "RequestCount", ReturnData: true}}},
+			err:   "expression cannot be used",
+		},
+		{
+			name:  "metric without period",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{{Id: "cpu", Namespace: "AWS/EC2", Metric: "CPUUtilization", Statistic: "Average", ReturnData: true}}},
+			err:   "are required for metric",
+		},
+		{
+			name: "invalid dimension",
+			alarm: AlarmConfigs{Metrics: []AlarmMetric{
+				{Id: "cpu", Namespace: "AWS/EC2", Metric: "CPUUtilization", Statistic: "Average", Period: 60, Dimensions: []string{"InstanceType"}, ReturnData: true},
+			}},
+			err: "dimension should be like key=value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.alarm.Name = "error-rate-high"
+
+			checkError(t, checkAlarmMetrics(tt.alarm), tt.err)
+		})
+	}
+}
+
+func TestCheckAlarms(t *testing.T) {
+	policies := []ScalePolicy{{Name: "scale-out"}}
+
+	tests := []struct {
+		name   string
+		alarms []AlarmConfigs
+		err    string
+	}{
+		{
+			name:   "policy name and ARN actions",
+			alarms: []AlarmConfigs{{Name: "cpu-high", AlarmActions: []string{"scale-out", "arn:aws:sns:ap-northeast-2:123456789012:alert"}}},
+		},
+		{
+			name:   "unknown policy",
+			alarms: []AlarmConfigs{{Name: "cpu-high", OKActions: []string{"scale-in"}}},
+			err:    "no scaling action exists",
+		},
+		{
+			name:   "duplicated names",
+			alarms: []AlarmConfigs{{Name: "cpu-high"}, {Name: "cpu-high"}},
+			err:    "duplicated",
+		},
+		{
+			name:   "invalid treat_missing_data",
+			alarms: []AlarmConfigs{{Name: "cpu-high", TreatMissingData: "zero"}},
+			err:    "treat_missing_data",
+		},
+		{
+			name:   "datapoints larger than periods",
+			alarms: []AlarmConfigs{{Name: "cpu-high", EvaluationPeriods: 2, DatapointsToAlarm: 3}},
+			err:    "datapoints_to_alarm",
+		},
+		{
+			name:   "error of metrics names the alarm",
+			alarms: []AlarmConfigs{{Name: "error-rate-high", Metrics: []AlarmMetric{{Id: "rate", Expression: "1"}}}},
+			err:    "exactly one of metrics should have return_data : error-rate-high",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkError(t, checkAlarms(tt.alarms, policies), tt.err)
+		})
+	}
+}
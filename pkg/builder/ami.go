@@ -0,0 +1,75 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	AMI_SELECTOR_NAME = "name:"
+	AMI_SELECTOR_TAG  = "tag:"
+	AMI_SELECTOR_SSM  = "ssm:"
+)
+
+type AmiSelector struct {
+	Name         string   `yaml:"name" description:"Name pattern of AMI. The latest matched AMI is used."`
+	Owners       []string `yaml:"owners" description:"Account IDs or aliases which own the AMI."`
+	Tags         []string `yaml:"tags" description:"Tags of AMI in 'key=value' format. The latest matched AMI is used."`
+	SSMParameter string   `yaml:"ssm_parameter" description:"SSM parameter which stores AMI ID."`
+}
+
+// ParseAmiSelector parses AMI selector from command line
+// Available formats are `name:<pattern>`, `tag:<key>=<value>,...` and `ssm:<parameter path>`.
+func ParseAmiSelector(ami, owners string) (AmiSelector, error) {
+	selector := AmiSelector{}
+	if len(owners) > 0 {
+		selector.Owners = strings.Split(owners, ",")
+	}
+
+	switch {
+	case strings.HasPrefix(ami, AMI_SELECTOR_NAME):
+		selector.Name = strings.TrimPrefix(ami, AMI_SELECTOR_NAME)
+	case strings.HasPrefix(ami, AMI_SELECTOR_TAG):
+		selector.Tags = strings.Split(strings.TrimPrefix(ami, AMI_SELECTOR_TAG), ",")
+	case strings.HasPrefix(ami, AMI_SELECTOR_SSM):
+		selector.SSMParameter = strings.TrimPrefix(ami, AMI_SELECTOR_SSM)
+	default:
+		return selector, fmt.Errorf("not available ami format : %s", ami)
+	}
+
+	return selector, checkAmiSelector(selector)
+}
+
+// GetAmiSelector returns the AMI id or selector of the region
+// The value from command line takes precedence over the manifest.
+func GetAmiSelector(config Config, region RegionConfig) (string, AmiSelector, error) {
+	if len(config.Ami) > 0 {
+		if strings.HasPrefix(config.Ami, "ami-") {
+			return config.Ami, AmiSelector{}, nil
+		}
+
+		selector, err := ParseAmiSelector(config.Ami, config.AmiOwners)
+		return "", selector, err
+	}
+
+	return region.AmiId, region.AmiSelector, nil
+}
+
+// checkAmiSelector checks validation of AMI selector
+func checkAmiSelector(selector AmiSelector) error {
+	if len(selector.SSMParameter) > 0 && (len(selector.Name) > 0 || len(selector.Tags) > 0 || len(selector.Owners) > 0) {
+		return fmt.Errorf("ssm_parameter cannot be used with name, owners or tags of ami_selector")
+	}
+
+	if len(selector.SSMParameter) == 0 && len(selector.Name) == 0 && len(selector.Tags) == 0 {
+		return fmt.Errorf("you have to specify name, tags or ssm_parameter in ami_selector")
+	}
+
+	for _, tag := range selector.Tags {
+		if kv := strings.SplitN(tag, "=", 2); len(kv) != 2 || len(kv[0]) == 0 {
+			return fmt.Errorf("tag of ami_selector should be like key=value : %s", tag)
+		}
+	}
+
+	return nil
+}
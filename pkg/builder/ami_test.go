@@ -0,0 +1,78 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAmiSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		ami      string
+		owners   string
+		expected AmiSelector
+		err      bool
+	}{
+		{
+			name:     "name pattern with owners",
+			ami:      "name:hello-*",
+			owners:   "self,123456789012",
+			expected: AmiSelector{Name: "hello-*", Owners: []string{"self", "123456789012"}},
+		},
+		{
+			name:     "multiple tags",
+			ami:      "tag:app=hello,env=dev",
+			expected: AmiSelector{Tags: []string{"app=hello", "env=dev"}},
+		},
+		{
+			name:     "ssm parameter",
+			ami:      "ssm:/goployer/hello/ami",
+			expected: AmiSelector{SSMParameter: "/goployer/hello/ami"},
+		},
+		{name: "unknown format", ami: "hello-*", err: true},
+		{name: "empty name", ami: "name:", err: true},
+		{name: "tag without value", ami: "tag:app", err: true},
+		{name: "tag without key", ami: "tag:=hello", err: true},
+		{name: "ssm parameter with owners", ami: "ssm:/goployer/hello/ami", owners: "self", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := ParseAmiSelector(tt.ami, tt.owners)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, but got %+v", selector)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(selector, tt.expected) {
+				t.Errorf("expected %+v, but got %+v", tt.expected, selector)
+			}
+		})
+	}
+}
+
+func TestGetAmiSelector(t *testing.T) {
+	region := RegionConfig{AmiId: "ami-0fedcba9876543210"}
+
+	// AMI id from command line takes precedence over the manifest
+	id, _, err := GetAmiSelector(Config{Ami: "ami-0123456789abcdef0"}, region)
+	if err != nil || id != "ami-0123456789abcdef0" {
+		t.Errorf("expected AMI id from command line, but got %s(%v)", id, err)
+	}
+
+	id, selector, err := GetAmiSelector(Config{Ami: "name:hello-*"}, region)
+	if err != nil || len(id) > 0 || selector.Name != "hello-*" {
+		t.Errorf("expected selector from command line, but got %s %+v(%v)", id, selector, err)
+	}
+
+	id, _, err = GetAmiSelector(Config{}, region)
+	if err != nil || id != "ami-0fedcba9876543210" {
+		t.Errorf("expected AMI id of the region, but got %s(%v)", id, err)
+	}
+}
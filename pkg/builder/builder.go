@@ -8,14 +8,27 @@ import (
 	Logger "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 )
 
 var (
 	NO_MANIFEST_EXISTS               = "Manifest file does not exist"
+	DEPLOY_COMMAND                   = "deploy"
+	SCHEMA_COMMAND                   = "schema"
+	VALIDATE_COMMAND                 = "validate"
+	yamlErrorLineRegex               = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
 	DFEAULT_SPOT_ALLOCATION_STRATEGY = "lowest-price"
 	availableBlockTypes              = []string{"io1", "gp2", "st1", "sc1"}
+	DEFAULT_HEALTHCHECK_TYPE         = "EC2"
+	DEFAULT_HEALTHCHECK_GRACE_PERIOD = int64(300)
+	availableHealthCheckTypes        = []string{"EC2", "ELB"}
+	availableTerminationPolicies     = []string{"OldestInstance", "NewestInstance", "OldestLaunchConfiguration", "OldestLaunchTemplate", "ClosestToNextInstanceHour", "AllocationStrategy", "Default"}
+	availableSuspendProcesses        = []string{"AlarmNotification", "AZRebalance", "ScheduledActions", "ReplaceUnhealthy", "HealthCheck", "InstanceRefresh"}
+	MIN_MAX_INSTANCE_LIFETIME        = int64(86400)
+	MAX_MAX_INSTANCE_LIFETIME        = int64(31536000)
 )
 
 type UserdataProvider interface {
@@ -40,6 +53,9 @@ type Builder struct {
 type Config struct {
 	Manifest              string
 	Ami                   string
+	AmiOwners             string
+	PackerManifest        string
+	PackerBuildUUID       string
 	Env                   string
 	Stack                 string
 	AssumeRole            string
@@ -59,10 +75,14 @@ type Config struct {
 }
 
 type YamlConfig struct {
-	Name     string   `yaml:"name"`
-	Userdata Userdata `yaml:"userdata"`
-	Tags     []string `yaml:"tags"`
-	Stacks   []Stack  `yaml:"stacks"`
+	Name     string   `yaml:"name" description:"Name of the application. It is used as the prefix of autoscaling group names."`
+	Userdata Userdata `yaml:"userdata" description:"Default userdata of stacks."`
+	Tags     []string `yaml:"tags" description:"Tags of all resources in 'key=value' format."`
+	Stacks   []Stack  `yaml:"stacks" description:"List of stacks to which the application can be deployed." required:"true"`
+
+	// Shared definitions which stacks can refer with yaml anchors
+	Autoscaling []ScalePolicy  `yaml:"autoscaling" description:"Shared scaling policies. They are not applied by themselves, and stacks refer to them with yaml anchors."`
+	Alarms      []AlarmConfigs `yaml:"alarms" description:"Shared CloudWatch alarms. They are not applied by themselves, and stacks refer to them with yaml anchors."`
 }
 
 type AWSConfig struct {
@@ -72,114 +92,201 @@ type AWSConfig struct {
 }
 
 type Userdata struct {
-	Type string `yaml:"type"`
-	Path string `yaml:"path"`
+	Type string `yaml:"type" description:"Where the userdata script is, 'local' or 's3'."`
+	Path string `yaml:"path" description:"Path of the userdata script."`
 }
 
 type ScalePolicy struct {
-	Name              string `yaml:"name"`
-	AdjustmentType    string `yaml:"adjustment_type"`
-	ScalingAdjustment int64  `yaml:"scaling_adjustment"`
-	Cooldown          int64  `yaml:"cooldown"`
+	Name                    string                      `yaml:"name" description:"Name of the scaling policy, which alarms refer in alarm_actions." required:"true"`
+	PolicyType              string                      `yaml:"policy_type" description:"Type of the scaling policy, 'SimpleScaling'(default), 'StepScaling' or 'TargetTrackingScaling'."`
+	AdjustmentType          string                      `yaml:"adjustment_type" description:"How scaling_adjustment is applied, e.g. 'ChangeInCapacity', 'ExactCapacity' or 'PercentChangeInCapacity'."`
+	ScalingAdjustment       int64                       `yaml:"scaling_adjustment" description:"Number of instances or percentage to scale by in simple scaling."`
+	Cooldown                int64                       `yaml:"cooldown" description:"Seconds to wait after simple scaling before another scaling activity starts."`
+	MetricAggregationType   string                      `yaml:"metric_aggregation_type" description:"Aggregation type of the alarm metric in step scaling, 'Minimum', 'Maximum' or 'Average'."`
+	EstimatedInstanceWarmup int64                       `yaml:"estimated_instance_warmup" description:"Seconds until a new instance contributes to the metric."`
+	MinAdjustmentMagnitude  int64                       `yaml:"min_adjustment_magnitude" description:"Minimum number of instances to scale by with PercentChangeInCapacity."`
+	StepAdjustments         []StepAdjustment            `yaml:"step_adjustments" description:"Steps of step scaling. Bounds are relative to the threshold of the alarm."`
+	TargetTracking          TargetTrackingConfiguration `yaml:"target_tracking" description:"Metric and target value of target tracking scaling."`
 }
 
 type AlarmConfigs struct {
-	Name              string
-	Namespace         string
-	Metric            string
-	Statistic         string
-	Comparison        string
-	Threshold         float64
-	Period            int64
-	EvaluationPeriods int64    `yaml:"evaluation_periods"`
-	AlarmActions      []string `yaml:"alarm_actions"`
+	Name                    string        `description:"Name of the alarm. The alarm is named with the autoscaling group name as prefix." required:"true"`
+	Namespace               string        `description:"Namespace of the metric."`
+	Metric                  string        `description:"Name of the metric."`
+	Statistic               string        `description:"Statistic of the metric, e.g. 'Average' or 'Sum'."`
+	Comparison              string        `description:"Comparison operator with threshold, e.g. 'GreaterThanThreshold'."`
+	Threshold               float64       `description:"Threshold of the metric."`
+	Period                  int64         `description:"Seconds of each evaluation period."`
+	EvaluationPeriods       int64         `yaml:"evaluation_periods" description:"Number of periods compared with threshold."`
+	DatapointsToAlarm       int64         `yaml:"datapoints_to_alarm" description:"Number of breaching datapoints in evaluation periods to go to alarm."`
+	AlarmActions            []string      `yaml:"alarm_actions" description:"Scaling policy names or ARNs triggered when the alarm goes to ALARM."`
+	OKActions               []string      `yaml:"ok_actions" description:"Scaling policy names or ARNs triggered when the alarm goes to OK."`
+	InsufficientDataActions []string      `yaml:"insufficient_data_actions" description:"Scaling policy names or ARNs triggered when the alarm goes to INSUFFICIENT_DATA."`
+	TreatMissingData        string        `yaml:"treat_missing_data" description:"How missing datapoints are treated, 'breaching', 'notBreaching', 'ignore' or 'missing'."`
+	Scope                   string        `yaml:"scope" description:"Default dimension of the metric, 'asg'(default), 'target_group' or 'none'."`
+	Dimensions              []string      `yaml:"dimensions" description:"Dimensions appended to the scope in 'name=value' format."`
+	Metrics                 []AlarmMetric `yaml:"metrics" description:"Metrics and expressions for metric math. It replaces namespace, metric and statistic."`
 }
 
 type Stack struct {
-	Stack                 string                `yaml:"stack"`
-	Account               string                `yaml:"account"`
-	Env                   string                `yaml:"env"`
-	ReplacementType       string                `yaml:"replacement_type"`
-	Userdata              Userdata              `yaml:"userdata"`
-	IamInstanceProfile    string                `yaml:"iam_instance_profile"`
-	AnsibleTags           string                `yaml:"ansible_tags"`
-	AssumeRole            string                `yaml:"assume_role"`
-	EbsOptimized          bool                  `yaml:"ebs_optimized"`
-	InstanceMarketOptions InstanceMarketOptions `yaml:"instance_market_options"`
-	MixedInstancesPolicy  MixedInstancesPolicy  `yaml:"mixed_instances_policy,omitempty"`
-	BlockDevices          []BlockDevice         `yaml:"block_devices"`
-	Capacity              Capacity              `yaml:"capacity"`
-	Autoscaling           []ScalePolicy         `yaml:"autoscaling"`
-	Alarms                []AlarmConfigs        `yaml:"alarms"`
-	LifecycleCallbacks    LifecycleCallbacks    `yaml:"lifecycle_callbacks"`
-	LifecycleHooks        LifecycleHooks        `yaml:"lifecycle_hooks"`
-	Regions               []RegionConfig        `yaml:"regions"`
+	Stack                  string                `yaml:"stack" description:"Name of the stack, which is selected with '--stack'." required:"true"`
+	Account                string                `yaml:"account" description:"Name of the AWS account."`
+	Env                    string                `yaml:"env" description:"Environment of the stack, e.g. dev, stage or prod."`
+	ReplacementType        string                `yaml:"replacement_type" description:"Deployment strategy. Only 'BlueGreen' is supported."`
+	Userdata               Userdata              `yaml:"userdata" description:"Userdata of instances. It overrides the default userdata."`
+	IamInstanceProfile     string                `yaml:"iam_instance_profile" description:"IAM instance profile of instances."`
+	AnsibleTags            string                `yaml:"ansible_tags" description:"Ansible tags which are set in tags of instances."`
+	AssumeRole             string                `yaml:"assume_role" description:"IAM role which goployer assumes for the stack."`
+	EbsOptimized           bool                  `yaml:"ebs_optimized" description:"Whether instances are EBS optimized."`
+	HealthCheckType        string                `yaml:"health_check_type" description:"Health check type of autoscaling group, 'EC2' or 'ELB'."`
+	HealthCheckGracePeriod int64                 `yaml:"health_check_grace_period" description:"Seconds before autoscaling group starts to check health of new instances."`
+	TerminationPolicies    []string              `yaml:"termination_policies" description:"Termination policies of autoscaling group."`
+	DefaultCooldown        int64                 `yaml:"default_cooldown" description:"Seconds between scaling activities of autoscaling group."`
+	MaxInstanceLifetime    int64                 `yaml:"max_instance_lifetime" description:"Maximum seconds an instance can be in service."`
+	CapacityRebalance      bool                  `yaml:"capacity_rebalance" description:"Whether spot instances at risk of interruption are replaced in advance."`
+	WarmPool               WarmPool              `yaml:"warm_pool" description:"Warm pool of pre-initialized instances."`
+	SuspendProcesses       []string              `yaml:"suspend_processes" description:"Autoscaling processes suspended during deployment."`
+	AmiCopy                bool                  `yaml:"ami_copy" description:"Whether AMI of the source region is copied to other regions."`
+	AmiCopySourceRegion    string                `yaml:"ami_copy_source_region" description:"Region of the AMI which is copied."`
+	InstanceMarketOptions  InstanceMarketOptions `yaml:"instance_market_options" description:"Spot instance settings."`
+	MixedInstancesPolicy   MixedInstancesPolicy  `yaml:"mixed_instances_policy,omitempty" description:"Mixed instances policy of on-demand and spot instances."`
+	BlockDevices           []BlockDevice         `yaml:"block_devices" description:"Block devices of instances."`
+	Capacity               Capacity              `yaml:"capacity" description:"Min, max and desired capacity of autoscaling group."`
+	Autoscaling            []ScalePolicy         `yaml:"autoscaling" description:"Scaling policies of autoscaling group."`
+	Alarms                 []AlarmConfigs        `yaml:"alarms" description:"CloudWatch alarms created for autoscaling group."`
+	ScheduledActions       []ScheduledAction     `yaml:"scheduled_actions" description:"Scheduled actions created on each new autoscaling group."`
+	LifecycleCallbacks     LifecycleCallbacks    `yaml:"lifecycle_callbacks" description:"Commands which run at each phase of deployment."`
+	LifecycleHooks         LifecycleHooks        `yaml:"lifecycle_hooks" description:"Lifecycle hooks of autoscaling group."`
+	LambdaHooks            LambdaHooks           `yaml:"lambda_hooks" description:"Lambda functions invoked at each phase of deployment."`
+	Notifications          Notifications         `yaml:"notifications" description:"Destinations of deployment events."`
+	Healthcheck            Healthcheck           `yaml:"healthcheck" description:"How health of new instances is checked."`
+	HealthGates            HealthGates           `yaml:"health_gates" description:"CloudWatch metrics which should be healthy before previous versions are deleted."`
+	Regions                []RegionConfig        `yaml:"regions" description:"Regions to which the stack is deployed concurrently."`
 }
 
 type LifecycleHooks struct {
-	LaunchTransition    []LifecycleHookSpecification `yaml:"launch_transition"`
-	TerminateTransition []LifecycleHookSpecification `yaml:"terminate_transition"`
+	LaunchTransition    []LifecycleHookSpecification `yaml:"launch_transition" description:"Lifecycle hooks applied when instances launch."`
+	TerminateTransition []LifecycleHookSpecification `yaml:"terminate_transition" description:"Lifecycle hooks applied when instances terminate."`
 }
 
 type LifecycleHookSpecification struct {
-	DefaultResult         string `yaml:"default_result"`
-	HeartbeatTimeout      int64  `yaml:"heartbeat_timeout"`
-	LifecycleHookName     string `yaml:"lifecycle_hook_name"`
-	NotificationMetadata  string `yaml:"notification_metadata"`
-	NotificationTargetARN string `yaml:"notification_target_arn"`
-	RoleARN               string `yaml:"role_arn"`
+	DefaultResult         string `yaml:"default_result" description:"Result applied when the hook times out, 'CONTINUE' or 'ABANDON'."`
+	HeartbeatTimeout      int64  `yaml:"heartbeat_timeout" description:"Seconds an instance stays in wait state before default_result is applied."`
+	LifecycleHookName     string `yaml:"lifecycle_hook_name" description:"Name of the lifecycle hook."`
+	NotificationMetadata  string `yaml:"notification_metadata" description:"Additional information sent to the notification target."`
+	NotificationTargetARN string `yaml:"notification_target_arn" description:"ARN of SNS topic or SQS queue which receives lifecycle notifications."`
+	RoleARN               string `yaml:"role_arn" description:"ARN of IAM role which publishes to the notification target."`
+
+	// goployer completes launch lifecycle action when readiness check passes
+	Readiness HealthcheckProvider `yaml:"readiness" description:"Health check of 'http' or 'ssm' type. goployer completes the launch lifecycle action when it passes."`
 }
 
 type InstanceMarketOptions struct {
-	MarketType  string      `yaml:"market_type"`
-	SpotOptions SpotOptions `yaml:"spot_options"`
+	MarketType  string      `yaml:"market_type" description:"Market type of instances. Only 'spot' is supported."`
+	SpotOptions SpotOptions `yaml:"spot_options" description:"Options of spot instances."`
 }
 
 type MixedInstancesPolicy struct {
-	Enabled                bool     `yaml:"enabled"`
-	Override               []string `yaml:"override_instance_types"`
-	OnDemandPercentage     int64    `yaml:"on_demand_percentage"`
-	SpotAllocationStrategy string   `yaml:"spot_allocation_strategy"`
-	SpotInstancePools      int64    `yaml:"spot_instance_pools"`
-	SpotMaxPrice           string   `yaml:"spot_max_price,omitempty"`
+	Enabled                bool     `yaml:"enabled" description:"Whether mixed instances policy is used."`
+	Override               []string `yaml:"override_instance_types" description:"Instance types which autoscaling group can launch."`
+	OnDemandPercentage     int64    `yaml:"on_demand_percentage" description:"Percentage of on-demand instances."`
+	SpotAllocationStrategy string   `yaml:"spot_allocation_strategy" description:"How spot instances are allocated, 'lowest-price'(default) or 'capacity-optimized'."`
+	SpotInstancePools      int64    `yaml:"spot_instance_pools" description:"Number of spot pools with lowest-price strategy."`
+	SpotMaxPrice           string   `yaml:"spot_max_price,omitempty" description:"Maximum price per hour of spot instances. Empty means on-demand price."`
 }
 
 type SpotOptions struct {
-	BlockDurationMinutes         int64  `yaml:"block_duration_minutes"`
-	InstanceInterruptionBehavior string `yaml:"instance_interruption_behavior"`
-	MaxPrice                     string `yaml:"max_price"`
-	SpotInstanceType             string `yaml:"spot_instance_type"`
+	BlockDurationMinutes         int64  `yaml:"block_duration_minutes" description:"Minutes of spot block, one of 60, 120, 180, 240, 300 and 360."`
+	InstanceInterruptionBehavior string `yaml:"instance_interruption_behavior" description:"What happens when spot instances are interrupted, 'hibernate', 'stop' or 'terminate'."`
+	MaxPrice                     string `yaml:"max_price" description:"Maximum price per hour of spot instances."`
+	SpotInstanceType             string `yaml:"spot_instance_type" description:"Type of spot request, 'one-time' or 'persistent'."`
 }
 
 type BlockDevice struct {
-	DeviceName string `yaml:"device_name"`
-	VolumeSize int64  `yaml:"volume_size"`
-	VolumeType string `yaml:"volume_type"`
-}
-
-type LifecycleCallbacks struct {
-	PreTerminatePastClusters []string `yaml:"pre_terminate_past_clusters"`
+	DeviceName string `yaml:"device_name" description:"Device name, e.g. /dev/xvda." required:"true"`
+	VolumeSize int64  `yaml:"volume_size" description:"Size of the volume in GiB."`
+	VolumeType string `yaml:"volume_type" description:"Type of the volume, 'gp2', 'io1', 'st1' or 'sc1'."`
 }
 
 type RegionConfig struct {
-	Region                 string   `yaml:"region"`
-	UsePublicSubnets       bool     `yaml:"use_public_subnets"`
-	InstanceType           string   `yaml:"instance_type"`
-	SshKey                 string   `yaml:"ssh_key"`
-	AmiId                  string   `yaml:"ami_id"`
-	VPC                    string   `yaml:"vpc"`
-	SecurityGroups         []string `yaml:"security_groups"`
-	HealthcheckLB          string   `yaml:"healthcheck_load_balancer"`
-	HealthcheckTargetGroup string   `yaml:"healthcheck_target_group"`
-	TargetGroups           []string `yaml:"target_groups"`
-	LoadBalancers          []string `yaml:"loadbalancers"`
-	AvailabilityZones      []string `yaml:"availability_zones"`
+	Region                 string                `yaml:"region" description:"ID of the region." required:"true"`
+	UsePublicSubnets       bool                  `yaml:"use_public_subnets" description:"Whether instances are launched in public subnets."`
+	InstanceType           string                `yaml:"instance_type" description:"Instance type." required:"true"`
+	SshKey                 string                `yaml:"ssh_key" description:"Name of the EC2 key pair."`
+	AmiId                  string                `yaml:"ami_id" description:"AMI ID of the region."`
+	AmiSelector            AmiSelector           `yaml:"ami_selector" description:"Selector which resolves AMI in the region."`
+	AmiCopyKmsKeyId        string                `yaml:"ami_copy_kms_key_id" description:"KMS key to encrypt the copied AMI."`
+	VPC                    string                `yaml:"vpc" description:"Name or ID of VPC."`
+	SecurityGroups         []string              `yaml:"security_groups" description:"Security groups of instances."`
+	HealthcheckLB          string                `yaml:"healthcheck_load_balancer" description:"Classic load balancer used for health check."`
+	HealthcheckTargetGroup string                `yaml:"healthcheck_target_group" description:"Target group used for health check."`
+	TargetGroups           []string              `yaml:"target_groups" description:"Target groups to which instances are attached."`
+	LoadBalancers          []string              `yaml:"loadbalancers" description:"Classic load balancers to which instances are attached."`
+	AvailabilityZones      []string              `yaml:"availability_zones" description:"Availability zones of instances."`
+	Capacity               RegionCapacity        `yaml:"capacity" description:"Capacity of the region. Only fields set here replace the ones of the stack."`
+	Autoscaling            []ScalePolicy         `yaml:"autoscaling" description:"Scaling policies of the region, used instead of the ones of the stack."`
+	Alarms                 []AlarmConfigs        `yaml:"alarms" description:"CloudWatch alarms of the region, used instead of the ones of the stack."`
+	ScheduledActions       []ScheduledAction     `yaml:"scheduled_actions" description:"Scheduled actions of the region, e.g. for business hours in its time zone."`
+	BlockDevices           []BlockDevice         `yaml:"block_devices" description:"Block devices of instances in the region."`
+	InstanceMarketOptions  InstanceMarketOptions `yaml:"instance_market_options" description:"Spot settings of the region, e.g. max price in the region."`
+	MixedInstancesPolicy   MixedInstancesPolicy  `yaml:"mixed_instances_policy,omitempty" description:"Mixed instances policy of the region, e.g. instance types available in the region."`
 }
 
 type Capacity struct {
-	Min     int64 `yaml:"min"`
-	Max     int64 `yaml:"max"`
-	Desired int64 `yaml:"desired"`
+	Min     int64 `yaml:"min" description:"Minimum size of autoscaling group."`
+	Max     int64 `yaml:"max" description:"Maximum size of autoscaling group."`
+	Desired int64 `yaml:"desired" description:"Desired capacity of autoscaling group. It should be between min and max."`
+}
+
+// RegionCapacity is capacity of the region
+// Fields are pointers so that explicit 0 can override the capacity of the stack.
+type RegionCapacity struct {
+	Min     *int64 `yaml:"min" description:"Minimum size of autoscaling group in the region."`
+	Max     *int64 `yaml:"max" description:"Maximum size of autoscaling group in the region."`
+	Desired *int64 `yaml:"desired" description:"Desired capacity of autoscaling group in the region."`
+}
+
+// ApplyRegionOverrides returns the effective stack configuration of the region.
+// Settings specified in the region override the ones of the stack.
+// Capacity is overridden field by field, so a region can change only desired capacity.
+func ApplyRegionOverrides(stack Stack, region RegionConfig) Stack {
+	if region.Capacity.Min != nil {
+		stack.Capacity.Min = *region.Capacity.Min
+	}
+
+	if region.Capacity.Max != nil {
+		stack.Capacity.Max = *region.Capacity.Max
+	}
+
+	if region.Capacity.Desired != nil {
+		stack.Capacity.Desired = *region.Capacity.Desired
+	}
+
+	if len(region.Autoscaling) > 0 {
+		stack.Autoscaling = region.Autoscaling
+	}
+
+	if len(region.Alarms) > 0 {
+		stack.Alarms = region.Alarms
+	}
+
+	if len(region.ScheduledActions) > 0 {
+		stack.ScheduledActions = region.ScheduledActions
+	}
+
+	if len(region.BlockDevices) > 0 {
+		stack.BlockDevices = region.BlockDevices
+	}
+
+	if !tool.IsZero(region.InstanceMarketOptions) {
+		stack.InstanceMarketOptions = region.InstanceMarketOptions
+	}
+
+	if !tool.IsZero(region.MixedInstancesPolicy) {
+		stack.MixedInstancesPolicy = region.MixedInstancesPolicy
+	}
+
+	return stack
 }
 
 func (l LocalProvider) Provide() string {
@@ -216,13 +323,16 @@ func NewBuilder() (Builder, error) {
 	// Set config
 	builder.Config = config
 
-	return builder.SetStacks(), nil
+	return builder.SetStacks()
 }
 
 // SetStacks set stack information
-func (b Builder) SetStacks() Builder {
+func (b Builder) SetStacks() (Builder, error) {
 
-	awsConfig, Stacks := parsingManifestFile(b.Config.Manifest)
+	awsConfig, Stacks, err := parsingManifestFile(b.Config.Manifest)
+	if err != nil {
+		return b, err
+	}
 
 	b.AwsConfig = awsConfig
 
@@ -232,6 +342,32 @@ func (b Builder) SetStacks() Builder {
 		}
 	}
 
+	if len(b.Config.PackerManifest) > 0 {
+		amis, err := ParsePackerManifest(b.Config.PackerManifest, b.Config.PackerBuildUUID)
+		if err != nil {
+			return b, err
+		}
+
+		for i, stack := range Stacks {
+			for j, region := range stack.Regions {
+				ami, ok := amis[region.Region]
+				if !ok {
+					if NeedAmiCopy(stack, region) {
+						continue
+					}
+
+					if stack.Stack == b.Config.Stack && (len(b.Config.Region) == 0 || b.Config.Region == region.Region) {
+						return b, fmt.Errorf("no AMI for %s exists in packer manifest : %s", region.Region, b.Config.PackerManifest)
+					}
+					continue
+				}
+
+				Stacks[i].Regions[j].AmiId = ami
+				Stacks[i].Regions[j].AmiSelector = AmiSelector{}
+			}
+		}
+	}
+
 	b.Stacks = Stacks
 
 	if len(b.Config.Env) == 0 {
@@ -242,33 +378,56 @@ func (b Builder) SetStacks() Builder {
 		}
 	}
 
-	return b
+	return b, nil
 }
 
 // Validation Check
 func (b Builder) CheckValidation() error {
+	if errs := b.ValidationErrors(); len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
+
+// ValidationErrors checks configurations and returns every problem found
+func (b Builder) ValidationErrors() []error {
+	errs := []error{}
 	target_ami := b.Config.Ami
 	target_region := b.Config.Region
 
 	// Check stack
 	if len(b.Config.Stack) == 0 {
-		return fmt.Errorf("you should choose at least one stack.")
+		errs = append(errs, fmt.Errorf("you should choose at least one stack."))
 	}
 
 	// Global AMI check
-	if len(target_region) == 0 && len(target_ami) != 0 && strings.HasPrefix(target_ami, "ami-") {
+	// AMI is copied to other regions if ami_copy is enabled
+	if len(target_region) == 0 && len(target_ami) != 0 && strings.HasPrefix(target_ami, "ami-") && !b.amiCopyEnabled() {
 		// One ami id cannot be used in different regions
-		return fmt.Errorf("one ami id cannot be used in different regions : %s", target_ami)
+		errs = append(errs, fmt.Errorf("one ami id cannot be used in different regions : %s", target_ami))
+	}
+
+	// Packer manifest check
+	if len(b.Config.PackerManifest) > 0 && len(target_ami) > 0 {
+		errs = append(errs, fmt.Errorf("you cannot specify the ami and packer-manifest at the same time"))
+	}
+
+	// AMI selector check
+	if len(target_ami) != 0 && !strings.HasPrefix(target_ami, "ami-") {
+		if _, err := ParseAmiSelector(target_ami, b.Config.AmiOwners); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	// check metric configuration file if metric feature is enabled
 	if !b.Config.DisableMetrics && !tool.FileExists(METRIC_YAML_PATH) {
-		return fmt.Errorf("no %s file exists", METRIC_YAML_PATH)
+		errs = append(errs, fmt.Errorf("no %s file exists", METRIC_YAML_PATH))
 	}
 
 	// check release notes
 	if len(b.Config.ReleaseNotes) > 0 && len(b.Config.ReleaseNotesBase64) > 0 {
-		return fmt.Errorf("you cannot specify the release-notes and release-notes-base64 at the same time")
+		errs = append(errs, fmt.Errorf("you cannot specify the release-notes and release-notes-base64 at the same time"))
 	}
 
 	// check validations in each stack
@@ -277,132 +436,309 @@ func (b Builder) CheckValidation() error {
 			continue
 		}
 
-		// Check AMI
-		// Check Autoscaling and Alarm setting
-		if len(stack.Autoscaling) != 0 && len(stack.Alarms) != 0 {
-			policies := []string{}
-			for _, scaling := range stack.Autoscaling {
-				if len(scaling.Name) == 0 {
-					return fmt.Errorf("autoscaling policy doesn't have a name.")
-				}
-				policies = append(policies, scaling.Name)
-			}
-			for _, alarm := range stack.Alarms {
-				for _, action := range alarm.AlarmActions {
-					if !tool.IsStringInArray(action, policies) {
-						return fmt.Errorf("no scaling action exists : %s", action)
-					}
-				}
-			}
+		errs = append(errs, checkStackValidation(stack)...)
+
+		for _, region := range stack.Regions {
+			errs = append(errs, checkRegionValidation(stack, region, target_ami)...)
 		}
+	}
 
-		// Check Spot Options
-		if len(stack.InstanceMarketOptions.MarketType) != 0 {
-			if stack.InstanceMarketOptions.MarketType != "spot" {
-				return fmt.Errorf("no valid market type : %s", stack.InstanceMarketOptions.MarketType)
-			}
+	if len(b.MetricConfig.Region) <= 0 {
+		errs = append(errs, fmt.Errorf("you do not specify the region for metrics"))
+	}
 
-			if stack.InstanceMarketOptions.SpotOptions.BlockDurationMinutes%60 != 0 || stack.InstanceMarketOptions.SpotOptions.BlockDurationMinutes > 360 {
-				return fmt.Errorf("block_duration_minutes should be one of [ 60, 120, 180, 240, 300, 360 ]")
-			}
+	if len(b.MetricConfig.Storage.Name) <= 0 {
+		errs = append(errs, fmt.Errorf("you do not specify the name of storage for metrics"))
+	}
 
-			if stack.InstanceMarketOptions.SpotOptions.SpotInstanceType == "persistent" && stack.InstanceMarketOptions.SpotOptions.InstanceInterruptionBehavior == "terminate" {
-				return fmt.Errorf("persistent type is not allowed with termiante behavior.")
-			}
+	return errs
+}
+
+// checkStackValidation checks settings of the stack which are shared by regions
+func checkStackValidation(stack Stack) []error {
+	errs := []error{}
+
+	// Check settings which can be overridden in each region
+	if err := checkStackSettings(stack); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, l := range stack.LifecycleHooks.LaunchTransition {
+		if len(l.NotificationTargetARN) > 0 && len(l.RoleARN) == 0 {
+			errs = append(errs, fmt.Errorf("role_arn is needed if `notification_target_arn` is not empty : %s", l.LifecycleHookName))
 		}
 
-		// Check block device setting
-		if len(stack.BlockDevices) > 0 {
-			dNames := []string{}
-			for _, block := range stack.BlockDevices {
-				if len(block.DeviceName) == 0 {
-					return fmt.Errorf("name of device is required.")
-				}
+		if len(l.RoleARN) > 0 && len(l.NotificationTargetARN) == 0 {
+			errs = append(errs, fmt.Errorf("notification_target_arn is needed if `role_arn` is not empty  : %s", l.LifecycleHookName))
+		}
 
-				if !tool.IsStringInArray(block.VolumeType, availableBlockTypes) {
-					return fmt.Errorf("not available volume type : %s", block.VolumeType)
-				}
+		if l.HeartbeatTimeout == 0 {
+			Logger.Warnf("you didn't specify the heartbeat timeout. you might have to wait too long time.")
+		}
 
-				if block.VolumeType == "st1" && block.VolumeSize < 500 {
-					return fmt.Errorf("volume size of st1 type should be larger than 500GiB")
-				}
+		if err := checkReadiness(l.Readiness); err != nil {
+			errs = append(errs, fmt.Errorf("%s : %s", err.Error(), l.LifecycleHookName))
+		}
+	}
 
-				if tool.IsStringInArray(block.DeviceName, dNames) {
-					return fmt.Errorf("device names are duplicated : %s", block.DeviceName)
-				} else {
-					dNames = append(dNames, block.DeviceName)
-				}
-			}
+	for _, l := range stack.LifecycleHooks.TerminateTransition {
+		if len(l.NotificationTargetARN) > 0 && len(l.RoleARN) == 0 {
+			errs = append(errs, fmt.Errorf("role_arn is needed if `notification_target_arn` is not empty : %s", l.LifecycleHookName))
 		}
 
-		if &stack.LifecycleHooks != nil {
-			if len(stack.LifecycleHooks.LaunchTransition) > 0 {
-				for _, l := range stack.LifecycleHooks.LaunchTransition {
-					if len(l.NotificationTargetARN) > 0 && len(l.RoleARN) == 0 {
-						return fmt.Errorf("role_arn is needed if `notification_target_arn` is not empty : %s", l.LifecycleHookName)
-					}
+		if len(l.RoleARN) > 0 && len(l.NotificationTargetARN) == 0 {
+			errs = append(errs, fmt.Errorf("notification_target_arn is needed if `role_arn` is not empty  : %s", l.LifecycleHookName))
+		}
 
-					if len(l.RoleARN) > 0 && len(l.NotificationTargetARN) == 0 {
-						return fmt.Errorf("notification_target_arn is needed if `role_arn` is not empty  : %s", l.LifecycleHookName)
-					}
+		if l.HeartbeatTimeout == 0 {
+			Logger.Warnf("you didn't specify the heartbeat timeout. you might have to wait too long time.")
+		}
 
-					if l.HeartbeatTimeout == 0 {
-						Logger.Warnf("you didn't specify the heartbeat timeout. you might have to wait too long time.")
-					}
-				}
-			}
+		if !tool.IsZero(l.Readiness) {
+			errs = append(errs, fmt.Errorf("readiness is only for launch_transition : %s", l.LifecycleHookName))
+		}
+	}
 
-			if len(stack.LifecycleHooks.TerminateTransition) > 0 {
-				for _, l := range stack.LifecycleHooks.TerminateTransition {
-					if len(l.NotificationTargetARN) > 0 && len(l.RoleARN) == 0 {
-						return fmt.Errorf("role_arn is needed if `notification_target_arn` is not empty : %s", l.LifecycleHookName)
-					}
+	checks := []error{
+		// Check autoscaling group settings
+		checkAutoscalingGroupSettings(stack),
+		// Check health check providers
+		checkHealthcheck(stack.Healthcheck.Operator, stack.Healthcheck.Providers),
+		checkHealthcheckSettings(stack.Healthcheck),
+		// Check health gates
+		checkHealthGates(stack.HealthGates),
+		// Check lifecycle callbacks
+		checkLifecycleCallbacks(stack.LifecycleCallbacks),
+		// Check lambda hooks
+		checkLambdaHooks(stack.LambdaHooks),
+		// Check notifications
+		checkNotifications(stack.Notifications),
+	}
 
-					if len(l.RoleARN) > 0 && len(l.NotificationTargetARN) == 0 {
-						return fmt.Errorf("notification_target_arn is needed if `role_arn` is not empty  : %s", l.LifecycleHookName)
-					}
+	for _, err := range checks {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-					if l.HeartbeatTimeout == 0 {
-						Logger.Warnf("you didn't specify the heartbeat timeout. you might have to wait too long time.")
-					}
-				}
-			}
+	// Check AMI copy
+	if stack.AmiCopy && !checkRegionExist(GetAmiCopySourceRegion(stack), stack.Regions) {
+		errs = append(errs, fmt.Errorf("ami_copy_source_region is not in the regions of stack : %s", stack.AmiCopySourceRegion))
+	}
+
+	return errs
+}
+
+// checkRegionValidation checks settings of the region and effective settings of the stack in the region
+func checkRegionValidation(stack Stack, region RegionConfig, target_ami string) []error {
+	errs := []error{}
+
+	if len(region.AmiCopyKmsKeyId) > 0 && !NeedAmiCopy(stack, region) {
+		errs = append(errs, fmt.Errorf("ami_copy_kms_key_id is only used for the region to which AMI is copied : %s", region.Region))
+	}
+
+	//Check ami id
+	if len(target_ami) == 0 && len(region.AmiId) == 0 && tool.IsZero(region.AmiSelector) && !NeedAmiCopy(stack, region) {
+		errs = append(errs, fmt.Errorf("you have to specify at least one ami id."))
+	}
+
+	if len(region.AmiId) > 0 && !tool.IsZero(region.AmiSelector) {
+		errs = append(errs, fmt.Errorf("you cannot specify the ami_id and ami_selector at the same time : %s", region.Region))
+	}
+
+	if !tool.IsZero(region.AmiSelector) {
+		if err := checkAmiSelector(region.AmiSelector); err != nil {
+			errs = append(errs, fmt.Errorf("%s in region %s", err.Error(), region.Region))
 		}
+	}
 
-		for _, region := range stack.Regions {
-			//Check ami id
-			if len(target_ami) == 0 && len(region.AmiId) == 0 {
-				return fmt.Errorf("you have to specify at least one ami id.")
-			}
+	//Check instance type
+	if len(region.InstanceType) == 0 {
+		errs = append(errs, fmt.Errorf("you have to specify the instance type."))
+	}
 
-			//Check instance type
-			if len(region.InstanceType) == 0 {
-				return fmt.Errorf("you have to specify the instance type.")
-			}
+	if stack.HealthCheckType == "ELB" && len(region.LoadBalancers) == 0 && len(region.TargetGroups) == 0 && len(region.HealthcheckLB) == 0 && len(region.HealthcheckTargetGroup) == 0 {
+		errs = append(errs, fmt.Errorf("ELB health check type needs load balancers or target groups in region %s", region.Region))
+	}
+
+	// Check effective settings of the region
+	effective := ApplyRegionOverrides(stack, region)
+	checks := []error{
+		checkStackSettings(effective),
+		checkHealthcheckTargets(stack.Healthcheck.Providers, region),
+		checkHealthGateTargets(stack.HealthGates, region),
+		checkAlarmTargets(effective.Alarms, region),
+		checkScalePolicyTargets(effective.Autoscaling, region),
+	}
+
+	for _, err := range checks {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s in region %s", err.Error(), region.Region))
+		}
+	}
+
+	return errs
+}
+
+// amiCopyEnabled checks if ami_copy is enabled in the target stack
+func (b Builder) amiCopyEnabled() bool {
+	for _, stack := range b.Stacks {
+		if stack.Stack == b.Config.Stack {
+			return stack.AmiCopy
+		}
+	}
+	return false
+}
+
+// GetAmiCopySourceRegion returns the region from which AMI is copied
+// By default, the first region of the stack is the source region.
+func GetAmiCopySourceRegion(stack Stack) string {
+	if len(stack.AmiCopySourceRegion) > 0 || len(stack.Regions) == 0 {
+		return stack.AmiCopySourceRegion
+	}
+	return stack.Regions[0].Region
+}
+
+// NeedAmiCopy checks if AMI of the region should be copied from the source region
+func NeedAmiCopy(stack Stack, region RegionConfig) bool {
+	return stack.AmiCopy && GetAmiCopySourceRegion(stack) != region.Region
+}
+
+// checkRegionExist checks if target region is in the regions
+func checkRegionExist(target string, regions []RegionConfig) bool {
+	for _, region := range regions {
+		if region.Region == target {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAutoscalingGroupSettings checks settings of autoscaling group
+func checkAutoscalingGroupSettings(stack Stack) error {
+	if len(stack.HealthCheckType) > 0 && !tool.IsStringInArray(stack.HealthCheckType, availableHealthCheckTypes) {
+		return fmt.Errorf("health_check_type should be one of %v : %s", availableHealthCheckTypes, stack.HealthCheckType)
+	}
+
+	if stack.HealthCheckGracePeriod < 0 {
+		return fmt.Errorf("health_check_grace_period cannot be negative : %d", stack.HealthCheckGracePeriod)
+	}
+
+	for _, policy := range stack.TerminationPolicies {
+		if !tool.IsStringInArray(policy, availableTerminationPolicies) {
+			return fmt.Errorf("termination policy should be one of %v : %s", availableTerminationPolicies, policy)
 		}
+	}
 
-		// check mixed instances policy
-		if stack.MixedInstancesPolicy.Enabled {
-			if len(stack.MixedInstancesPolicy.SpotAllocationStrategy) == 0 {
-				stack.MixedInstancesPolicy.SpotAllocationStrategy = DFEAULT_SPOT_ALLOCATION_STRATEGY
+	// Launch, Terminate and AddToLoadBalancer are required for deployment
+	for _, process := range stack.SuspendProcesses {
+		if !tool.IsStringInArray(process, availableSuspendProcesses) {
+			return fmt.Errorf("process to suspend should be one of %v : %s", availableSuspendProcesses, process)
+		}
+	}
+
+	if stack.DefaultCooldown < 0 {
+		return fmt.Errorf("default_cooldown cannot be negative : %d", stack.DefaultCooldown)
+	}
+
+	if stack.MaxInstanceLifetime != 0 && (stack.MaxInstanceLifetime < MIN_MAX_INSTANCE_LIFETIME || stack.MaxInstanceLifetime > MAX_MAX_INSTANCE_LIFETIME) {
+		return fmt.Errorf("max_instance_lifetime should be 0 or between %d and %d seconds : %d", MIN_MAX_INSTANCE_LIFETIME, MAX_MAX_INSTANCE_LIFETIME, stack.MaxInstanceLifetime)
+	}
+
+	return nil
+}
+
+// checkCapacity checks that capacity satisfies min <= desired <= max
+func checkCapacity(capacity Capacity) error {
+	if capacity.Min < 0 || capacity.Max < 0 || capacity.Desired < 0 {
+		return fmt.Errorf("capacity cannot be negative : %+v", capacity)
+	}
+
+	if capacity.Min > capacity.Desired || capacity.Desired > capacity.Max {
+		return fmt.Errorf("capacity should satisfy min <= desired <= max : %+v", capacity)
+	}
+
+	return nil
+}
+
+// checkStackSettings checks settings of stack which can be overridden by region
+func checkStackSettings(stack Stack) error {
+	// Check capacity
+	if err := checkCapacity(stack.Capacity); err != nil {
+		return err
+	}
+
+	// Check Autoscaling and Alarm setting
+	if err := checkScalePolicies(stack.Autoscaling, stack.Alarms); err != nil {
+		return err
+	}
+
+	if err := checkAlarms(stack.Alarms, stack.Autoscaling); err != nil {
+		return err
+	}
+
+	// Check scheduled actions
+	if err := checkScheduledActions(stack.ScheduledActions); err != nil {
+		return err
+	}
+
+	// Check warm pool
+	if err := checkWarmPool(stack); err != nil {
+		return err
+	}
+
+	// Check Spot Options
+	if len(stack.InstanceMarketOptions.MarketType) != 0 {
+		if stack.InstanceMarketOptions.MarketType != "spot" {
+			return fmt.Errorf("no valid market type : %s", stack.InstanceMarketOptions.MarketType)
+		}
+
+		if stack.InstanceMarketOptions.SpotOptions.BlockDurationMinutes%60 != 0 || stack.InstanceMarketOptions.SpotOptions.BlockDurationMinutes > 360 {
+			return fmt.Errorf("block_duration_minutes should be one of [ 60, 120, 180, 240, 300, 360 ]")
+		}
+
+		if stack.InstanceMarketOptions.SpotOptions.SpotInstanceType == "persistent" && stack.InstanceMarketOptions.SpotOptions.InstanceInterruptionBehavior == "terminate" {
+			return fmt.Errorf("persistent type is not allowed with termiante behavior.")
+		}
+	}
+
+	// Check block device setting
+	if len(stack.BlockDevices) > 0 {
+		dNames := []string{}
+		for _, block := range stack.BlockDevices {
+			if len(block.DeviceName) == 0 {
+				return fmt.Errorf("name of device is required.")
 			}
 
-			if stack.MixedInstancesPolicy.SpotAllocationStrategy != "lowest-price" && stack.MixedInstancesPolicy.SpotInstancePools > 0 {
-				return fmt.Errorf("you can only set spot_instance_pools with lowest-price spot_allocation_strategy")
+			if !tool.IsStringInArray(block.VolumeType, availableBlockTypes) {
+				return fmt.Errorf("not available volume type : %s", block.VolumeType)
 			}
 
-			if len(stack.MixedInstancesPolicy.Override) <= 0 {
-				return fmt.Errorf("you have to set at least one instance type to use in override")
+			if block.VolumeType == "st1" && block.VolumeSize < 500 {
+				return fmt.Errorf("volume size of st1 type should be larger than 500GiB")
+			}
+
+			if tool.IsStringInArray(block.DeviceName, dNames) {
+				return fmt.Errorf("device names are duplicated : %s", block.DeviceName)
+			} else {
+				dNames = append(dNames, block.DeviceName)
 			}
 		}
 	}
 
-	if len(b.MetricConfig.Region) <= 0 {
-		return fmt.Errorf("you do not specify the region for metrics")
-	}
+	// check mixed instances policy
+	if stack.MixedInstancesPolicy.Enabled {
+		if len(stack.MixedInstancesPolicy.SpotAllocationStrategy) == 0 {
+			stack.MixedInstancesPolicy.SpotAllocationStrategy = DFEAULT_SPOT_ALLOCATION_STRATEGY
+		}
 
-	if len(b.MetricConfig.Storage.Name) <= 0 {
-		return fmt.Errorf("you do not specify the name of storage for metrics")
+		if stack.MixedInstancesPolicy.SpotAllocationStrategy != "lowest-price" && stack.MixedInstancesPolicy.SpotInstancePools > 0 {
+			return fmt.Errorf("you can only set spot_instance_pools with lowest-price spot_allocation_strategy")
+		}
+
+		if len(stack.MixedInstancesPolicy.Override) <= 0 {
+			return fmt.Errorf("you have to set at least one instance type to use in override")
+		}
 	}
 
 	return nil
@@ -428,6 +764,13 @@ Stack
 	for _, stack := range b.Stacks {
 		if stack.Stack == target_stack {
 			summary = append(summary, printEnvironment(stack))
+
+			for _, region := range stack.Regions {
+				if len(b.Config.Region) > 0 && b.Config.Region != region.Region {
+					continue
+				}
+				summary = append(summary, printRegion(ApplyRegionOverrides(stack, region), region))
+			}
 		}
 	}
 
@@ -441,6 +784,14 @@ Environment             : %s
 IAM Instance Profile    : %s
 Ansible tags            : %s 
 Capacity                : %+v
+Health Check Type       : %s
+Health Check Grace      : %d
+Termination Policies    : %v
+Default Cooldown        : %d
+Max Instance Lifetime   : %d
+Capacity Rebalance      : %t
+Warm Pool               : %+v
+Suspend Processes       : %v
 MixedInstancesPolicy
 - Enabled 			: %t
 - Override 			: %+v
@@ -457,6 +808,99 @@ MixedInstancesPolicy
 		stack.IamInstanceProfile,
 		stack.AnsibleTags,
 		stack.Capacity,
+		GetHealthCheckType(stack),
+		GetHealthCheckGracePeriod(stack),
+		stack.TerminationPolicies,
+		stack.DefaultCooldown,
+		stack.MaxInstanceLifetime,
+		stack.CapacityRebalance,
+		stack.WarmPool,
+		stack.SuspendProcesses,
+		stack.MixedInstancesPolicy.Enabled,
+		stack.MixedInstancesPolicy.Override,
+		stack.MixedInstancesPolicy.OnDemandPercentage,
+		stack.MixedInstancesPolicy.SpotAllocationStrategy,
+		stack.MixedInstancesPolicy.SpotInstancePools,
+		stack.MixedInstancesPolicy.SpotMaxPrice,
+	)
+	return summary
+}
+
+// GetHealthCheckType returns health check type of autoscaling group
+func GetHealthCheckType(stack Stack) string {
+	if len(stack.HealthCheckType) == 0 {
+		return DEFAULT_HEALTHCHECK_TYPE
+	}
+	return stack.HealthCheckType
+}
+
+// GetHealthCheckGracePeriod returns health check grace period of autoscaling group
+func GetHealthCheckGracePeriod(stack Stack) int64 {
+	if stack.HealthCheckGracePeriod == 0 {
+		return DEFAULT_HEALTHCHECK_GRACE_PERIOD
+	}
+	return stack.HealthCheckGracePeriod
+}
+
+// GetReleaseNotes returns release notes of the deployment
+// Release notes in base64 are decoded.
+func GetReleaseNotes(config Config) string {
+	if len(config.ReleaseNotesBase64) == 0 {
+		return config.ReleaseNotes
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(config.ReleaseNotesBase64)
+	if err != nil {
+		return config.ReleaseNotesBase64
+	}
+
+	return string(decoded)
+}
+
+// printRegion prints the effective configurations of the region
+func printRegion(stack Stack, region RegionConfig) string {
+	formatting := `[ %s ]
+Instance Type           : %s
+Capacity                : %+v
+Autoscaling             : %s
+Alarms                  : %s
+Scheduled Actions       : %s
+Block Devices           : %+v
+InstanceMarketOptions   : %+v
+MixedInstancesPolicy
+- Enabled 			: %t
+- Override 			: %+v
+- OnDemandPercentage  		: %d
+- SpotAllocationStrategy 	: %s
+- SpotInstancePools 		: %d
+- SpotMaxPrice 			: %s
+
+============================================================`
+
+	policies := []string{}
+	for _, policy := range stack.Autoscaling {
+		policies = append(policies, policy.Name)
+	}
+
+	alarms := []string{}
+	for _, alarm := range stack.Alarms {
+		alarms = append(alarms, alarm.Name)
+	}
+
+	scheduledActions := []string{}
+	for _, action := range stack.ScheduledActions {
+		scheduledActions = append(scheduledActions, action.Summary())
+	}
+
+	summary := fmt.Sprintf(formatting,
+		region.Region,
+		region.InstanceType,
+		stack.Capacity,
+		strings.Join(policies, ", "),
+		strings.Join(alarms, ", "),
+		strings.Join(scheduledActions, ", "),
+		stack.BlockDevices,
+		stack.InstanceMarketOptions,
 		stack.MixedInstancesPolicy.Enabled,
 		stack.MixedInstancesPolicy.Override,
 		stack.MixedInstancesPolicy.OnDemandPercentage,
@@ -468,17 +912,18 @@ MixedInstancesPolicy
 }
 
 // Parsing Manifest File
-func parsingManifestFile(manifest string) (AWSConfig, []Stack) {
+// Unknown fields in manifest are not allowed.
+func parsingManifestFile(manifest string) (AWSConfig, []Stack, error) {
 	yamlConfig := YamlConfig{}
 	yamlFile, err := ioutil.ReadFile(manifest)
 	if err != nil {
 		Logger.Errorf("Error reading YAML file: %s\n", err)
-		return AWSConfig{}, nil
+		return AWSConfig{}, nil, err
 	}
 
-	err = yaml.Unmarshal(yamlFile, &yamlConfig)
+	err = yaml.UnmarshalStrict(yamlFile, &yamlConfig)
 	if err != nil {
-		tool.FatalError(err)
+		return AWSConfig{}, nil, manifestError(manifest, err)
 	}
 
 	awsConfig := AWSConfig{
@@ -489,13 +934,55 @@ func parsingManifestFile(manifest string) (AWSConfig, []Stack) {
 
 	Stacks := yamlConfig.Stacks
 
-	return awsConfig, Stacks
+	return awsConfig, Stacks, nil
+}
+
+// manifestError formats yaml errors with position in the manifest file
+func manifestError(manifest string, err error) error {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+
+	ret := []string{}
+	for _, message := range messages {
+		matched := yamlErrorLineRegex.FindStringSubmatch(message)
+		if len(matched) == 0 {
+			ret = append(ret, fmt.Sprintf("%s: %s", manifest, message))
+			continue
+		}
+		ret = append(ret, fmt.Sprintf("%s:%s: %s", manifest, matched[1], matched[2]))
+	}
+
+	return fmt.Errorf("invalid manifest file : %s", strings.Join(ret, ", "))
+}
+
+// ParseCommand returns the command to run
+// If no command is specified, then deploy command is selected.
+func ParseCommand() string {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		return os.Args[1]
+	}
+
+	return DEPLOY_COMMAND
+}
+
+// commandArguments returns arguments for flags after the command
+func commandArguments() []string {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		return os.Args[2:]
+	}
+
+	return os.Args[1:]
 }
 
 // Parsing Config from command
 func argumentParsing() Config {
 	manifest := flag.String("manifest", "", "The manifest configuration file to use.")
-	ami := flag.String("ami", "", "The AMI to use for the servers.")
+	ami := flag.String("ami", "", "The AMI to use for the servers. You can select AMI with name:<pattern>, tag:<key>=<value>,... or ssm:<parameter path>.")
+	amiOwners := flag.String("ami-owners", "", "Comma-delimited list of AMI owners to search with the AMI selector. Default is self.")
+	packerManifest := flag.String("packer-manifest", "", "The packer manifest file which has AMIs for regions.")
+	packerBuildUUID := flag.String("packer-build-uuid", "", "The packer run uuid of build to use in packer manifest. Default is the last run.")
 	env := flag.String("env", "", "The environment that is being deployed into.")
 	stack := flag.String("stack", "", "An ordered, comma-delimited list of stacks that should be deployed.")
 	assumeRole := flag.String("assume-role", "", "The Role ARN to assume into")
@@ -512,11 +999,14 @@ func argumentParsing() Config {
 	releaseNotesBase64 := flag.String("release-notes-base64", "", "base64 encoded string of release note for the current deployment")
 	forceManifestCapacity := flag.Bool("force-manifest-capacity", false, "Force-apply the capacity of instances in the manifest file")
 
-	flag.Parse()
+	flag.CommandLine.Parse(commandArguments())
 
 	config := Config{
 		Manifest:              *manifest,
 		Ami:                   *ami,
+		AmiOwners:             *amiOwners,
+		PackerManifest:        *packerManifest,
+		PackerBuildUUID:       *packerBuildUUID,
 		Env:                   *env,
 		Stack:                 *stack,
 		Region:                *region,
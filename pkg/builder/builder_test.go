@@ -0,0 +1,34 @@
+package builder
+
+import (
+	"testing"
+)
+
+func TestApplyRegionOverridesCapacity(t *testing.T) {
+	zero, three := int64(0), int64(3)
+
+	tests := []struct {
+		name     string
+		region   RegionCapacity
+		expected Capacity
+	}{
+		{name: "nothing set keeps stack", region: RegionCapacity{}, expected: Capacity{Min: 1, Max: 4, Desired: 2}},
+		{name: "only desired set", region: RegionCapacity{Desired: &three}, expected: Capacity{Min: 1, Max: 4, Desired: 3}},
+		{name: "explicit zero overrides", region: RegionCapacity{Min: &zero, Desired: &zero}, expected: Capacity{Min: 0, Max: 4, Desired: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack := Stack{Capacity: Capacity{Min: 1, Max: 4, Desired: 2}}
+
+			ret := ApplyRegionOverrides(stack, RegionConfig{Capacity: tt.region})
+			if ret.Capacity != tt.expected {
+				t.Errorf("expected capacity %+v, but got %+v", tt.expected, ret.Capacity)
+			}
+
+			if err := checkCapacity(ret.Capacity); err != nil {
+				t.Errorf("merged capacity should be valid : %s", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package builder
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"strings"
+)
+
+var (
+	METRIC_SCOPE_ASG               = "asg"
+	METRIC_SCOPE_TARGET_GROUP      = "target_group"
+	METRIC_SCOPE_NONE              = "none"
+	DEFAULT_HEALTH_GATE_WINDOW     = int64(300)
+	DEFAULT_HEALTH_GATE_PERIOD     = int64(60)
+	availableMetricScopes          = []string{METRIC_SCOPE_ASG, METRIC_SCOPE_TARGET_GROUP, METRIC_SCOPE_NONE}
+	availableHealthGateStatistics  = []string{"Average", "Sum", "Minimum", "Maximum", "SampleCount"}
+	availableHealthGateComparisons = []string{"GreaterThanThreshold", "GreaterThanOrEqualToThreshold", "LessThanThreshold", "LessThanOrEqualToThreshold"}
+	MISSING_DATA_BREACHING         = "breaching"
+	MISSING_DATA_NOT_BREACHING     = "notBreaching"
+	availableGateMissingData       = []string{MISSING_DATA_BREACHING, MISSING_DATA_NOT_BREACHING}
+)
+
+type HealthGates struct {
+	Window    int64        `yaml:"window" description:"Seconds to watch metrics after new instances are healthy. (default: 300)"`
+	OnFailure string       `yaml:"on_failure" description:"What happens when a gate fails, 'fail'(default) or 'rollback'."`
+	Gates     []HealthGate `yaml:"gates" description:"CloudWatch metrics which should not be breached."`
+}
+
+type HealthGate struct {
+	Name               string   `yaml:"name" description:"Name of the health gate." required:"true"`
+	Namespace          string   `yaml:"namespace" description:"Namespace of the metric." required:"true"`
+	Metric             string   `yaml:"metric" description:"Name of the metric." required:"true"`
+	Statistic          string   `yaml:"statistic" description:"Statistic of the metric, e.g. 'Sum', 'Average' or 'p99'."`
+	Scope              string   `yaml:"scope" description:"Dimension of the new deployment added to the metric, 'asg'(default), 'target_group' or 'none'."`
+	Dimensions         []string `yaml:"dimensions" description:"Additional dimensions of the metric in 'name=value' format."`
+	Comparison         string   `yaml:"comparison" description:"Comparison operator which means breach, e.g. 'GreaterThanThreshold'."`
+	Threshold          float64  `yaml:"threshold" description:"Threshold of the metric."`
+	Period             int64    `yaml:"period" description:"Seconds of each datapoint. (default: 60)"`
+	DatapointsToBreach int64    `yaml:"datapoints_to_breach" description:"Number of breaching datapoints to fail the gate. (default: 1)"`
+	TreatMissingData   string   `yaml:"treat_missing_data" description:"How the gate is treated if the metric has no datapoint during the window, 'breaching'(default) or 'notBreaching'."`
+}
+
+// GetHealthGateWindow returns seconds to watch metrics of health gates
+func GetHealthGateWindow(gates HealthGates) int64 {
+	if gates.Window == 0 {
+		return DEFAULT_HEALTH_GATE_WINDOW
+	}
+	return gates.Window
+}
+
+// IsExtendedStatistic checks if the statistic is percentile like p99
+func IsExtendedStatistic(statistic string) bool {
+	return strings.HasPrefix(statistic, "p")
+}
+
+// IsMissingDataBreached checks if the gate fails when the metric has no datapoint during the window
+// Wrong namespace, metric or dimensions have no datapoint, so missing data is breaching by default.
+func (h HealthGate) IsMissingDataBreached() bool {
+	return h.TreatMissingData != MISSING_DATA_NOT_BREACHING
+}
+
+// IsBreached checks if the value breaches threshold of the health gate
+func (h HealthGate) IsBreached(value float64) bool {
+	switch h.Comparison {
+	case "GreaterThanThreshold":
+		return value > h.Threshold
+	case "GreaterThanOrEqualToThreshold":
+		return value >= h.Threshold
+	case "LessThanThreshold":
+		return value < h.Threshold
+	case "LessThanOrEqualToThreshold":
+		return value <= h.Threshold
+	}
+	return false
+}
+
+// checkHealthGates checks validation of health gates
+func checkHealthGates(gates HealthGates) error {
+	if len(gates.Gates) == 0 {
+		if !tool.IsZero(gates) {
+			return fmt.Errorf("health_gates should have at least one gate")
+		}
+		return nil
+	}
+
+	if gates.Window < 0 {
+		return fmt.Errorf("window of health_gates cannot be negative : %d", gates.Window)
+	}
+
+	if len(gates.OnFailure) > 0 && !tool.IsStringInArray(gates.OnFailure, availableFailureActions) {
+		return fmt.Errorf("on_failure of health_gates should be one of %v : %s", availableFailureActions, gates.OnFailure)
+	}
+
+	for _, gate := range gates.Gates {
+		if len(gate.Name) == 0 || len(gate.Namespace) == 0 || len(gate.Metric) == 0 {
+			return fmt.Errorf("name, namespace and metric are required for health gate")
+		}
+
+		if !IsExtendedStatistic(gate.Statistic) && !tool.IsStringInArray(gate.Statistic, availableHealthGateStatistics) {
+			return fmt.Errorf("statistic of health gate should be one of %v or percentile like p99 : %s", availableHealthGateStatistics, gate.Name)
+		}
+
+		if len(gate.Scope) > 0 && !tool.IsStringInArray(gate.Scope, availableMetricScopes) {
+			return fmt.Errorf("scope of health gate should be one of %v : %s", availableMetricScopes, gate.Name)
+		}
+
+		if !tool.IsStringInArray(gate.Comparison, availableHealthGateComparisons) {
+			return fmt.Errorf("comparison of health gate should be one of %v : %s", availableHealthGateComparisons, gate.Name)
+		}
+
+		for _, dimension := range gate.Dimensions {
+			if len(strings.Split(dimension, "=")) != 2 {
+				return fmt.Errorf("dimension of health gate should be like key=value : %s", dimension)
+			}
+		}
+
+		if gate.Period < 0 || gate.Period%60 != 0 {
+			return fmt.Errorf("period of health gate should be a multiple of 60 : %s", gate.Name)
+		}
+
+		if gate.DatapointsToBreach < 0 {
+			return fmt.Errorf("datapoints_to_breach of health gate cannot be negative : %s", gate.Name)
+		}
+
+		if len(gate.TreatMissingData) > 0 && !tool.IsStringInArray(gate.TreatMissingData, availableGateMissingData) {
+			return fmt.Errorf("treat_missing_data of health gate should be one of %v : %s", availableGateMissingData, gate.Name)
+		}
+	}
+
+	return nil
+}
+
+// checkHealthGateTargets checks if the region has targets of health gates
+func checkHealthGateTargets(gates HealthGates, region RegionConfig) error {
+	for _, gate := range gates.Gates {
+		if gate.Scope == METRIC_SCOPE_TARGET_GROUP && len(GetPrimaryTargetGroup(region)) == 0 {
+			return fmt.Errorf("no target group exists for health gate %s", gate.Name)
+		}
+	}
+
+	return nil
+}
+
+// GetPrimaryTargetGroup returns target group which represents the region
+// This is used for health gates and scaling policies scoped to target group.
+func GetPrimaryTargetGroup(region RegionConfig) string {
+	if len(region.HealthcheckTargetGroup) > 0 {
+		return region.HealthcheckTargetGroup
+	}
+
+	if len(region.TargetGroups) > 0 {
+		return region.TargetGroups[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,318 @@
+package builder
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"math"
+	"regexp"
+	"time"
+)
+
+var (
+	HEALTHCHECK_TARGET_GROUP  = "target_group"
+	HEALTHCHECK_CLASSIC_ELB   = "classic_elb"
+	HEALTHCHECK_ASG           = "asg"
+	HEALTHCHECK_COMBINATION   = "combination"
+	HEALTHCHECK_HTTP          = "http"
+	HEALTHCHECK_SSM           = "ssm"
+	OPERATOR_AND              = "and"
+	OPERATOR_OR               = "or"
+	availableHealthchecks     = []string{HEALTHCHECK_TARGET_GROUP, HEALTHCHECK_CLASSIC_ELB, HEALTHCHECK_ASG, HEALTHCHECK_COMBINATION, HEALTHCHECK_HTTP, HEALTHCHECK_SSM}
+	availableOperators        = []string{OPERATOR_AND, OPERATOR_OR}
+	availableHTTPSchemes      = []string{"http", "https"}
+	PHASE_DEPLOY              = "deploy"
+	PHASE_HEALTHCHECK         = "healthcheck"
+	PHASE_HEALTH_GATE         = "health_gate"
+	PHASE_TERMINATION         = "termination"
+	DEFAULT_MAX_POLL_INTERVAL = int64(600)
+	ON_FAILURE_FAIL           = "fail"
+	ON_FAILURE_ROLLBACK       = "rollback"
+	availableFailureActions   = []string{ON_FAILURE_FAIL, ON_FAILURE_ROLLBACK}
+)
+
+type Healthcheck struct {
+	Operator               string                `yaml:"operator" description:"How providers are combined, 'and'(default) or 'or'."`
+	Providers              []HealthcheckProvider `yaml:"providers" description:"Health check providers of new instances. They are chosen from load balancing resources of the region if empty."`
+	PollInterval           int64                 `yaml:"poll_interval" description:"Seconds between polls. (default: 60)"`
+	Backoff                float64               `yaml:"backoff" description:"Multiplier of poll interval after each poll."`
+	MaxPollInterval        int64                 `yaml:"max_poll_interval" description:"Maximum seconds between polls with backoff. (default: 600)"`
+	MinHealthyCount        int64                 `yaml:"min_healthy_count" description:"Number of healthy instances to pass. (default: desired capacity)"`
+	MinHealthyPercentage   int64                 `yaml:"min_healthy_percentage" description:"Percentage of desired capacity to pass. The larger one of count and percentage is used."`
+	ConsecutiveSuccesses   int64                 `yaml:"consecutive_successes" description:"Number of polls in a row which meet the threshold. (default: 1)"`
+	Timeouts               PhaseTimeouts         `yaml:"timeouts" description:"Minutes for each phase of deployment."`
+	LaunchFailureThreshold int64                 `yaml:"launch_failure_threshold" description:"Number of launch failures after which deployment fails without waiting for timeout. 0 disables the check."`
+	OnFailure              string                `yaml:"on_failure" description:"What happens to the new autoscaling group on launch failures, 'fail'(default) or 'rollback'."`
+}
+
+// PhaseTimeouts are timeouts of each deployment phase in minutes
+type PhaseTimeouts struct {
+	Deploy      int64 `yaml:"deploy" description:"Minutes to create the new autoscaling group, including AMI copy."`
+	Healthcheck int64 `yaml:"healthcheck" description:"Minutes for new instances to become healthy."`
+	HealthGate  int64 `yaml:"health_gate" description:"Minutes to evaluate health gates."`
+	Termination int64 `yaml:"termination" description:"Minutes to drain and terminate previous autoscaling groups."`
+}
+
+type HealthcheckProvider struct {
+	Type      string                `yaml:"type" description:"Type of the provider, e.g. 'target_group', 'classic_elb', 'asg', 'http', 'ssm' or 'combination'." required:"true"`
+	Operator  string                `yaml:"operator" description:"How providers of combination are combined, 'and' or 'or'."`
+	Providers []HealthcheckProvider `yaml:"providers" description:"Providers combined by combination type."`
+	HTTP      HTTPHealthcheck       `yaml:"http" description:"Settings of http type."`
+	SSM       SSMHealthcheck        `yaml:"ssm" description:"Settings of ssm type."`
+}
+
+type HTTPHealthcheck struct {
+	Scheme               string `yaml:"scheme" description:"Scheme of the request, 'http'(default) or 'https'."`
+	Path                 string `yaml:"path" description:"Path of the request. (default: /)"`
+	Port                 int64  `yaml:"port" description:"Port of the instance to probe." required:"true"`
+	ExpectedCodes        []int  `yaml:"expected_codes" description:"Status codes of healthy response. (default: [200])"`
+	BodyMatch            string `yaml:"body_match" description:"Regular expression which response body should match."`
+	ConsecutiveSuccesses int64  `yaml:"consecutive_successes" description:"Number of successful probes in a row to be healthy. (default: 1)"`
+	Timeout              int64  `yaml:"timeout" description:"Seconds for each request. (default: 5)"`
+	InsecureSkipVerify   bool   `yaml:"insecure_skip_verify" description:"Whether TLS certificate of the instance is not verified."`
+}
+
+type SSMHealthcheck struct {
+	Commands []string `yaml:"commands" description:"Shell commands which should exit with zero in healthy instances." required:"true"`
+	Timeout  int64    `yaml:"timeout" description:"Seconds to wait for results of commands. (default: 30, minimum: 30)"`
+}
+
+// GetHealthcheckProviders returns health check providers of the region
+// If no provider is specified, then target groups, classic load balancers or autoscaling group is checked in order.
+func GetHealthcheckProviders(healthcheck Healthcheck, region RegionConfig) []HealthcheckProvider {
+	if len(healthcheck.Providers) > 0 {
+		return healthcheck.Providers
+	}
+
+	if len(region.HealthcheckTargetGroup) > 0 || len(region.TargetGroups) > 0 {
+		return []HealthcheckProvider{{Type: HEALTHCHECK_TARGET_GROUP}}
+	}
+
+	if len(region.HealthcheckLB) > 0 || len(region.LoadBalancers) > 0 {
+		return []HealthcheckProvider{{Type: HEALTHCHECK_CLASSIC_ELB}}
+	}
+
+	return []HealthcheckProvider{{Type: HEALTHCHECK_ASG}}
+}
+
+// GetPollInterval returns interval before the next poll
+// If backoff is set, the interval grows exponentially with the number of polls up to max_poll_interval.
+func GetPollInterval(healthcheck Healthcheck, polls int) time.Duration {
+	interval := float64(tool.POLLING_SLEEP_TIME / time.Second)
+	if healthcheck.PollInterval > 0 {
+		interval = float64(healthcheck.PollInterval)
+	}
+
+	if healthcheck.Backoff > 1 {
+		maxInterval := float64(DEFAULT_MAX_POLL_INTERVAL)
+		if healthcheck.MaxPollInterval > 0 {
+			maxInterval = float64(healthcheck.MaxPollInterval)
+		}
+		interval = math.Min(interval*math.Pow(healthcheck.Backoff, float64(polls)), maxInterval)
+	}
+
+	return time.Duration(interval) * time.Second
+}
+
+// GetHealthyThreshold returns the number of healthy instances needed
+// By default, all desired instances should be healthy.
+func GetHealthyThreshold(healthcheck Healthcheck, desired int64) int64 {
+	if healthcheck.MinHealthyCount == 0 && healthcheck.MinHealthyPercentage == 0 {
+		return desired
+	}
+
+	threshold := healthcheck.MinHealthyCount
+	byPercentage := int64(math.Ceil(float64(desired*healthcheck.MinHealthyPercentage) / 100))
+	if byPercentage > threshold {
+		threshold = byPercentage
+	}
+
+	return threshold
+}
+
+// GetLaunchFailureThreshold returns the number of launch failures to give up deployment
+// Checking launch failures is disabled if the threshold is not set.
+func GetLaunchFailureThreshold(healthcheck Healthcheck) int64 {
+	return healthcheck.LaunchFailureThreshold
+}
+
+// GetPhaseTimeout returns timeout of the phase in minutes
+// Zero means that only the global timeout is applied.
+func GetPhaseTimeout(healthcheck Healthcheck, phase string) int64 {
+	switch phase {
+	case PHASE_DEPLOY:
+		return healthcheck.Timeouts.Deploy
+	case PHASE_HEALTHCHECK:
+		return healthcheck.Timeouts.Healthcheck
+	case PHASE_HEALTH_GATE:
+		return healthcheck.Timeouts.HealthGate
+	case PHASE_TERMINATION:
+		return healthcheck.Timeouts.Termination
+	}
+	return 0
+}
+
+// checkHealthcheckSettings checks validation of polling settings
+func checkHealthcheckSettings(healthcheck Healthcheck) error {
+	if healthcheck.PollInterval < 0 || healthcheck.MaxPollInterval < 0 {
+		return fmt.Errorf("poll_interval and max_poll_interval of healthcheck cannot be negative")
+	}
+
+	if healthcheck.MaxPollInterval > 0 && healthcheck.MaxPollInterval < healthcheck.PollInterval {
+		return fmt.Errorf("max_poll_interval should be larger than poll_interval : %d < %d", healthcheck.MaxPollInterval, healthcheck.PollInterval)
+	}
+
+	if healthcheck.Backoff != 0 && healthcheck.Backoff < 1 {
+		return fmt.Errorf("backoff of healthcheck should be larger than or equal to 1 : %v", healthcheck.Backoff)
+	}
+
+	if healthcheck.MinHealthyCount < 0 || healthcheck.ConsecutiveSuccesses < 0 {
+		return fmt.Errorf("min_healthy_count and consecutive_successes of healthcheck cannot be negative")
+	}
+
+	if healthcheck.MinHealthyPercentage < 0 || healthcheck.MinHealthyPercentage > 100 {
+		return fmt.Errorf("min_healthy_percentage of healthcheck should be between 0 and 100 : %d", healthcheck.MinHealthyPercentage)
+	}
+
+	if healthcheck.LaunchFailureThreshold < 0 {
+		return fmt.Errorf("launch_failure_threshold of healthcheck cannot be negative : %d", healthcheck.LaunchFailureThreshold)
+	}
+
+	if len(healthcheck.OnFailure) > 0 && !tool.IsStringInArray(healthcheck.OnFailure, availableFailureActions) {
+		return fmt.Errorf("on_failure of healthcheck should be one of %v : %s", availableFailureActions, healthcheck.OnFailure)
+	}
+
+	if healthcheck.Timeouts.Deploy < 0 || healthcheck.Timeouts.Healthcheck < 0 || healthcheck.Timeouts.HealthGate < 0 || healthcheck.Timeouts.Termination < 0 {
+		return fmt.Errorf("timeouts of healthcheck cannot be negative")
+	}
+
+	return nil
+}
+
+// checkHealthcheck checks validation of health check providers
+func checkHealthcheck(operator string, providers []HealthcheckProvider) error {
+	if len(operator) > 0 && !tool.IsStringInArray(operator, availableOperators) {
+		return fmt.Errorf("operator of healthcheck should be one of %v : %s", availableOperators, operator)
+	}
+
+	for _, provider := range providers {
+		if !tool.IsStringInArray(provider.Type, availableHealthchecks) {
+			return fmt.Errorf("type of healthcheck provider should be one of %v : %s", availableHealthchecks, provider.Type)
+		}
+
+		if provider.Type != HEALTHCHECK_HTTP && !tool.IsZero(provider.HTTP) {
+			return fmt.Errorf("http is only for %s type of healthcheck provider", HEALTHCHECK_HTTP)
+		}
+
+		if provider.Type == HEALTHCHECK_HTTP {
+			if err := checkHTTPHealthcheck(provider.HTTP); err != nil {
+				return err
+			}
+		}
+
+		if provider.Type != HEALTHCHECK_SSM && !tool.IsZero(provider.SSM) {
+			return fmt.Errorf("ssm is only for %s type of healthcheck provider", HEALTHCHECK_SSM)
+		}
+
+		if provider.Type == HEALTHCHECK_SSM {
+			if len(provider.SSM.Commands) == 0 {
+				return fmt.Errorf("commands are required for %s type of healthcheck provider", HEALTHCHECK_SSM)
+			}
+
+			// SSM Run Command requires at least 30 seconds of timeout
+			if provider.SSM.Timeout != 0 && provider.SSM.Timeout < 30 {
+				return fmt.Errorf("timeout of ssm healthcheck should be at least 30 seconds : %d", provider.SSM.Timeout)
+			}
+		}
+
+		if provider.Type != HEALTHCHECK_COMBINATION {
+			if len(provider.Providers) > 0 || len(provider.Operator) > 0 {
+				return fmt.Errorf("operator and providers are only for %s type of healthcheck provider", HEALTHCHECK_COMBINATION)
+			}
+			continue
+		}
+
+		if len(provider.Providers) == 0 {
+			return fmt.Errorf("%s type of healthcheck provider should have at least one provider", HEALTHCHECK_COMBINATION)
+		}
+
+		if err := checkHealthcheck(provider.Operator, provider.Providers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkHTTPHealthcheck checks validation of http health check
+func checkHTTPHealthcheck(h HTTPHealthcheck) error {
+	if h.Port <= 0 || h.Port > 65535 {
+		return fmt.Errorf("port of http healthcheck should be between 1 and 65535 : %d", h.Port)
+	}
+
+	if len(h.Scheme) > 0 && !tool.IsStringInArray(h.Scheme, availableHTTPSchemes) {
+		return fmt.Errorf("scheme of http healthcheck should be one of %v : %s", availableHTTPSchemes, h.Scheme)
+	}
+
+	if len(h.Path) > 0 && h.Path[0] != '/' {
+		return fmt.Errorf("path of http healthcheck should start with / : %s", h.Path)
+	}
+
+	for _, code := range h.ExpectedCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("expected code of http healthcheck is not valid : %d", code)
+		}
+	}
+
+	if len(h.BodyMatch) > 0 {
+		if _, err := regexp.Compile(h.BodyMatch); err != nil {
+			return fmt.Errorf("body_match of http healthcheck is not valid regular expression : %s", err.Error())
+		}
+	}
+
+	if h.ConsecutiveSuccesses < 0 || h.Timeout < 0 {
+		return fmt.Errorf("consecutive_successes and timeout of http healthcheck cannot be negative")
+	}
+
+	return nil
+}
+
+// checkHealthcheckTargets checks if the region has targets of health check providers
+func checkHealthcheckTargets(providers []HealthcheckProvider, region RegionConfig) error {
+	for _, provider := range providers {
+		switch provider.Type {
+		case HEALTHCHECK_TARGET_GROUP:
+			if len(region.HealthcheckTargetGroup) == 0 && len(region.TargetGroups) == 0 {
+				return fmt.Errorf("no target group exists for %s healthcheck", provider.Type)
+			}
+		case HEALTHCHECK_CLASSIC_ELB:
+			if len(region.HealthcheckLB) == 0 && len(region.LoadBalancers) == 0 {
+				return fmt.Errorf("no load balancer exists for %s healthcheck", provider.Type)
+			}
+		case HEALTHCHECK_COMBINATION:
+			if err := checkHealthcheckTargets(provider.Providers, region); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// HasReadiness checks if goployer should complete the launch lifecycle hook
+func HasReadiness(hook LifecycleHookSpecification) bool {
+	return len(hook.Readiness.Type) > 0
+}
+
+// checkReadiness checks readiness check of launch lifecycle hook
+// Only http and ssm are available because instances are not registered to load balancers yet.
+func checkReadiness(readiness HealthcheckProvider) error {
+	if tool.IsZero(readiness) {
+		return nil
+	}
+
+	if readiness.Type != HEALTHCHECK_HTTP && readiness.Type != HEALTHCHECK_SSM {
+		return fmt.Errorf("type of readiness should be one of [%s %s] : %s", HEALTHCHECK_HTTP, HEALTHCHECK_SSM, readiness.Type)
+	}
+
+	return checkHealthcheck("", []HealthcheckProvider{readiness})
+}
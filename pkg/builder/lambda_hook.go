@@ -0,0 +1,45 @@
+package builder
+
+import (
+	"fmt"
+)
+
+type LambdaHooks struct {
+	PreDeploy    []LambdaHook `yaml:"pre_deploy" description:"Functions invoked before the new autoscaling group is created."`
+	PostHealthy  []LambdaHook `yaml:"post_healthy" description:"Functions invoked after the new autoscaling group passes health checks."`
+	PreTerminate []LambdaHook `yaml:"pre_terminate" description:"Functions invoked before previous autoscaling groups are cleaned up."`
+}
+
+// LambdaHook is a Lambda function invoked synchronously at the phase of deployment
+// The phase fails if the function fails.
+type LambdaHook struct {
+	FunctionName string `yaml:"function_name" description:"Name or ARN of the Lambda function." required:"true"`
+	Qualifier    string `yaml:"qualifier" description:"Version or alias of the function."`
+}
+
+// GetLambdaHooks returns Lambda hooks of the phase
+func GetLambdaHooks(hooks LambdaHooks, phase string) []LambdaHook {
+	switch phase {
+	case CALLBACK_PHASE_PRE_DEPLOY:
+		return hooks.PreDeploy
+	case CALLBACK_PHASE_POST_HEALTHY:
+		return hooks.PostHealthy
+	case CALLBACK_PHASE_PRE_TERMINATE:
+		return hooks.PreTerminate
+	}
+
+	return nil
+}
+
+// checkLambdaHooks checks settings of Lambda hooks
+func checkLambdaHooks(hooks LambdaHooks) error {
+	for _, phase := range []string{CALLBACK_PHASE_PRE_DEPLOY, CALLBACK_PHASE_POST_HEALTHY, CALLBACK_PHASE_PRE_TERMINATE} {
+		for _, hook := range GetLambdaHooks(hooks, phase) {
+			if len(hook.FunctionName) == 0 {
+				return fmt.Errorf("function_name is required for %s lambda hook", phase)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package builder
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+var (
+	CALLBACK_FAILURE_ABORT    = "abort"
+	CALLBACK_FAILURE_CONTINUE = "continue"
+	CALLBACK_FAILURE_RETRY    = "retry"
+
+	CALLBACK_PHASE_PRE_DEPLOY    = "pre_deploy"
+	CALLBACK_PHASE_POST_HEALTHY  = "post_healthy"
+	CALLBACK_PHASE_PRE_TERMINATE = "pre_terminate"
+	CALLBACK_PHASE_POST_CLEANUP  = "post_cleanup"
+	CALLBACK_PHASE_ON_FAILURE    = "on_failure"
+
+	CALLBACK_TARGET_NEW      = "new"
+	CALLBACK_TARGET_PREVIOUS = "previous"
+	CALLBACK_TARGET_LOCAL    = "local"
+
+	DEFAULT_CALLBACK_TIMEOUT = int64(300)
+	DEFAULT_CALLBACK_RETRIES = int64(3)
+	MIN_CALLBACK_TIMEOUT     = int64(30)
+
+	availableCallbackFailurePolicies = []string{CALLBACK_FAILURE_ABORT, CALLBACK_FAILURE_CONTINUE, CALLBACK_FAILURE_RETRY}
+
+	// Instances of new autoscaling group do not exist before deployment,
+	// and instances of previous ones are terminated after cleanup.
+	availableCallbackTargets = map[string][]string{
+		CALLBACK_PHASE_PRE_DEPLOY:   {CALLBACK_TARGET_PREVIOUS, CALLBACK_TARGET_LOCAL},
+		CALLBACK_PHASE_POST_HEALTHY: {CALLBACK_TARGET_NEW, CALLBACK_TARGET_PREVIOUS, CALLBACK_TARGET_LOCAL},
+		CALLBACK_PHASE_POST_CLEANUP: {CALLBACK_TARGET_NEW, CALLBACK_TARGET_LOCAL},
+		CALLBACK_PHASE_ON_FAILURE:   {CALLBACK_TARGET_NEW, CALLBACK_TARGET_PREVIOUS, CALLBACK_TARGET_LOCAL},
+	}
+)
+
+type LifecycleCallbacks struct {
+	PreDeploy                []Callback `yaml:"pre_deploy" description:"Callbacks before the new autoscaling group is created."`
+	PostHealthy              []Callback `yaml:"post_healthy" description:"Callbacks after the new autoscaling group is healthy and passes health gates."`
+	PreTerminatePastClusters []string   `yaml:"pre_terminate_past_clusters" description:"Commands run in instances of previous autoscaling groups before cleanup."`
+	PostCleanup              []Callback `yaml:"post_cleanup" description:"Callbacks after previous autoscaling groups are terminated."`
+	OnFailure                []Callback `yaml:"on_failure" description:"Callbacks when deployment fails."`
+	Timeout                  int64      `yaml:"timeout" description:"Default seconds to wait for results of callbacks. (default: 300)"`
+	FailurePolicy            string     `yaml:"failure_policy" description:"Default policy of failed callbacks, 'continue'(default), 'abort' or 'retry'."`
+	Retries                  int64      `yaml:"retries" description:"Default number of retries with retry policy. (default: 3)"`
+}
+
+// Callback runs commands in instances with SSM or in the host running goployer
+// Timeout, failure policy and retries of lifecycle_callbacks are used if not specified.
+type Callback struct {
+	Name          string   `yaml:"name" description:"Name of the callback."`
+	Target        string   `yaml:"target" description:"Where commands run, 'new', 'previous' or 'local'."`
+	Commands      []string `yaml:"commands" description:"Shell commands of the callback." required:"true"`
+	Timeout       int64    `yaml:"timeout" description:"Seconds to wait for results of commands."`
+	FailurePolicy string   `yaml:"failure_policy" description:"What happens when commands fail, 'continue', 'abort' or 'retry'."`
+	Retries       int64    `yaml:"retries" description:"Number of retries with retry policy."`
+}
+
+// GetCallbacks returns callbacks of the phase with settings of lifecycle_callbacks
+func GetCallbacks(callbacks LifecycleCallbacks, phase string) []Callback {
+	list := []Callback{}
+	switch phase {
+	case CALLBACK_PHASE_PRE_DEPLOY:
+		list = callbacks.PreDeploy
+	case CALLBACK_PHASE_POST_HEALTHY:
+		list = callbacks.PostHealthy
+	case CALLBACK_PHASE_PRE_TERMINATE:
+		if len(callbacks.PreTerminatePastClusters) > 0 {
+			list = []Callback{{
+				Name:     "pre_terminate_past_clusters",
+				Target:   CALLBACK_TARGET_PREVIOUS,
+				Commands: callbacks.PreTerminatePastClusters,
+			}}
+		}
+	case CALLBACK_PHASE_POST_CLEANUP:
+		list = callbacks.PostCleanup
+	case CALLBACK_PHASE_ON_FAILURE:
+		list = callbacks.OnFailure
+	}
+
+	ret := []Callback{}
+	for i, callback := range list {
+		if len(callback.Name) == 0 {
+			callback.Name = fmt.Sprintf("%s[%d]", phase, i)
+		}
+
+		if callback.Timeout == 0 {
+			callback.Timeout = callbacks.Timeout
+		}
+
+		if len(callback.FailurePolicy) == 0 {
+			callback.FailurePolicy = callbacks.FailurePolicy
+			if callback.Retries == 0 {
+				callback.Retries = callbacks.Retries
+			}
+		}
+
+		ret = append(ret, callback)
+	}
+
+	return ret
+}
+
+// GetCallbackTimeout returns timeout of the callback in seconds
+func GetCallbackTimeout(callback Callback) int64 {
+	if callback.Timeout == 0 {
+		return DEFAULT_CALLBACK_TIMEOUT
+	}
+	return callback.Timeout
+}
+
+// GetCallbackFailurePolicy returns what to do when the callback fails
+// Deployment continues by default like before.
+func GetCallbackFailurePolicy(callback Callback) string {
+	if len(callback.FailurePolicy) == 0 {
+		return CALLBACK_FAILURE_CONTINUE
+	}
+	return callback.FailurePolicy
+}
+
+// GetCallbackAttempts returns the number of attempts of the callback
+func GetCallbackAttempts(callback Callback) int64 {
+	if GetCallbackFailurePolicy(callback) != CALLBACK_FAILURE_RETRY {
+		return 1
+	}
+
+	if callback.Retries == 0 {
+		return DEFAULT_CALLBACK_RETRIES + 1
+	}
+	return callback.Retries + 1
+}
+
+// checkLifecycleCallbacks checks settings of lifecycle callbacks
+func checkLifecycleCallbacks(callbacks LifecycleCallbacks) error {
+	if err := checkCallbackSettings("lifecycle_callbacks", callbacks.Timeout, callbacks.FailurePolicy, callbacks.Retries); err != nil {
+		return err
+	}
+
+	for _, phase := range []string{CALLBACK_PHASE_PRE_DEPLOY, CALLBACK_PHASE_POST_HEALTHY, CALLBACK_PHASE_POST_CLEANUP, CALLBACK_PHASE_ON_FAILURE} {
+		targets := availableCallbackTargets[phase]
+		for _, callback := range GetCallbacks(callbacks, phase) {
+			if !tool.IsStringInArray(callback.Target, targets) {
+				return fmt.Errorf("target of %s callback should be one of %v : %s", phase, targets, callback.Name)
+			}
+
+			if len(callback.Commands) == 0 {
+				return fmt.Errorf("commands are required for callback : %s", callback.Name)
+			}
+
+			if err := checkCallbackSettings(callback.Name, callback.Timeout, callback.FailurePolicy, callback.Retries); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkCallbackSettings checks timeout, failure policy and retries of callbacks
+func checkCallbackSettings(name string, timeout int64, policy string, retries int64) error {
+	if timeout != 0 && timeout < MIN_CALLBACK_TIMEOUT {
+		return fmt.Errorf("timeout of %s should be larger than %d seconds : %d", name, MIN_CALLBACK_TIMEOUT, timeout)
+	}
+
+	if len(policy) > 0 && !tool.IsStringInArray(policy, availableCallbackFailurePolicies) {
+		return fmt.Errorf("failure_policy of %s should be one of %v : %s", name, availableCallbackFailurePolicies, policy)
+	}
+
+	if retries < 0 {
+		return fmt.Errorf("retries of %s cannot be negative : %d", name, retries)
+	}
+
+	if retries > 0 && policy != CALLBACK_FAILURE_RETRY {
+		return fmt.Errorf("retries of %s is only used with failure_policy retry", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package builder
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"net/url"
+)
+
+var (
+	NOTIFY_EVENT_STARTED         = "started"
+	NOTIFY_EVENT_REGION_DEPLOYED = "region_deployed"
+	NOTIFY_EVENT_HEALTHY         = "healthy"
+	NOTIFY_EVENT_WAITING         = "waiting"
+	NOTIFY_EVENT_CLEANUP         = "cleanup"
+	NOTIFY_EVENT_FAILED          = "failed"
+	NOTIFY_EVENT_DONE            = "done"
+
+	DEFAULT_SMTP_PORT = int64(587)
+
+	availableNotifyEvents = []string{
+		NOTIFY_EVENT_STARTED,
+		NOTIFY_EVENT_REGION_DEPLOYED,
+		NOTIFY_EVENT_HEALTHY,
+		NOTIFY_EVENT_WAITING,
+		NOTIFY_EVENT_CLEANUP,
+		NOTIFY_EVENT_FAILED,
+		NOTIFY_EVENT_DONE,
+	}
+)
+
+// Notifications are destinations of deployment events of the stack
+// If nothing is configured, slack with SLACK_TOKEN and SLACK_CHANNEL is used.
+type Notifications struct {
+	Slack    []SlackNotification   `yaml:"slack" description:"Slack channels which receive events."`
+	Webhooks []WebhookNotification `yaml:"webhooks" description:"HTTP endpoints which receive events in JSON."`
+	Teams    []TeamsNotification   `yaml:"teams" description:"Microsoft Teams connectors which receive events."`
+	Email    []EmailNotification   `yaml:"email" description:"SMTP servers and recipients which receive events."`
+}
+
+// SlackNotification posts messages to the channel with the token in SLACK_TOKEN
+type SlackNotification struct {
+	Channel string   `yaml:"channel" description:"Slack channel. It overrides SLACK_CHANNEL."`
+	Events  []string `yaml:"events" description:"Events sent to the channel. (default: all)"`
+}
+
+// WebhookNotification posts events in JSON to the url
+// Body is signed with HMAC-SHA256 if the environment variable of secret is set.
+type WebhookNotification struct {
+	Url       string            `yaml:"url" description:"URL of the webhook." required:"true"`
+	SecretEnv string            `yaml:"secret_env" description:"Environment variable of the secret which signs body with HMAC-SHA256."`
+	Headers   map[string]string `yaml:"headers" description:"Additional headers of requests."`
+	Events    []string          `yaml:"events" description:"Events sent to the webhook. (default: all)"`
+}
+
+// TeamsNotification posts message cards to the incoming webhook of Microsoft Teams connector
+type TeamsNotification struct {
+	WebhookUrl string   `yaml:"webhook_url" description:"Incoming webhook URL of the connector." required:"true"`
+	Events     []string `yaml:"events" description:"Events sent to the connector. (default: all)"`
+}
+
+// EmailNotification sends mails with SMTP
+// Password is read from the environment variable, not from the manifest.
+type EmailNotification struct {
+	Host        string   `yaml:"host" description:"Host of SMTP server." required:"true"`
+	Port        int64    `yaml:"port" description:"Port of SMTP server."`
+	From        string   `yaml:"from" description:"Sender address." required:"true"`
+	To          []string `yaml:"to" description:"Recipient addresses." required:"true"`
+	Username    string   `yaml:"username" description:"Username of SMTP authentication."`
+	PasswordEnv string   `yaml:"password_env" description:"Environment variable of the password of SMTP authentication."`
+	Events      []string `yaml:"events" description:"Events sent with email. (default: all)"`
+}
+
+// HasNotifications checks if any destination is configured
+func HasNotifications(notifications Notifications) bool {
+	return len(notifications.Slack) > 0 || len(notifications.Webhooks) > 0 || len(notifications.Teams) > 0 || len(notifications.Email) > 0
+}
+
+// GetSmtpPort returns port of SMTP server
+func GetSmtpPort(email EmailNotification) int64 {
+	if email.Port == 0 {
+		return DEFAULT_SMTP_PORT
+	}
+	return email.Port
+}
+
+// checkNotifications checks settings of notifications
+func checkNotifications(notifications Notifications) error {
+	for _, slack := range notifications.Slack {
+		if err := checkNotifyEvents("slack", slack.Events); err != nil {
+			return err
+		}
+	}
+
+	for _, webhook := range notifications.Webhooks {
+		if err := checkNotifyUrl("url of webhook", webhook.Url); err != nil {
+			return err
+		}
+
+		if err := checkNotifyEvents("webhook", webhook.Events); err != nil {
+			return err
+		}
+	}
+
+	for _, teams := range notifications.Teams {
+		if err := checkNotifyUrl("webhook_url of teams", teams.WebhookUrl); err != nil {
+			return err
+		}
+
+		if err := checkNotifyEvents("teams", teams.Events); err != nil {
+			return err
+		}
+	}
+
+	for _, email := range notifications.Email {
+		if len(email.Host) == 0 {
+			return fmt.Errorf("host is required for email notification")
+		}
+
+		if email.Port < 0 || email.Port > 65535 {
+			return fmt.Errorf("port of email notification is not valid : %d", email.Port)
+		}
+
+		if len(email.From) == 0 || len(email.To) == 0 {
+			return fmt.Errorf("from and to are required for email notification : %s", email.Host)
+		}
+
+		if len(email.Username) == 0 && len(email.PasswordEnv) > 0 {
+			return fmt.Errorf("username is required to use password_env of email notification : %s", email.Host)
+		}
+
+		if err := checkNotifyEvents("email", email.Events); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkNotifyUrl checks if the url is an absolute http(s) url
+func checkNotifyUrl(name, rawUrl string) error {
+	if len(rawUrl) == 0 {
+		return fmt.Errorf("%s is required", name)
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		// url is not printed because webhook urls usually contain secrets
+		return fmt.Errorf("%s should be http or https url", name)
+	}
+
+	return nil
+}
+
+// checkNotifyEvents checks if events are available
+func checkNotifyEvents(name string, events []string) error {
+	for _, event := range events {
+		if !tool.IsStringInArray(event, availableNotifyEvents) {
+			return fmt.Errorf("events of %s notification should be one of %v : %s", name, availableNotifyEvents, event)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+var (
+	PACKER_AMAZON_BUILDER_PREFIX = "amazon-"
+)
+
+type PackerManifest struct {
+	Builds      []PackerBuild `json:"builds"`
+	LastRunUUID string        `json:"last_run_uuid"`
+}
+
+type PackerBuild struct {
+	Name          string `json:"name"`
+	BuilderType   string `json:"builder_type"`
+	BuildTime     int64  `json:"build_time"`
+	ArtifactId    string `json:"artifact_id"`
+	PackerRunUUID string `json:"packer_run_uuid"`
+}
+
+// ParsePackerManifest returns AMI ids for each region in the packer manifest
+// Artifacts of the build with uuid are used. If uuid is empty, then the last run is used.
+// Builds which are not amazon builders and artifacts which are not AMIs are skipped.
+func ParsePackerManifest(path, uuid string) (map[string]string, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := PackerManifest{}
+	if err := json.Unmarshal(file, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid packer manifest %s : %s", path, err.Error())
+	}
+
+	if len(uuid) == 0 {
+		uuid = manifest.LastRunUUID
+	}
+
+	amis := map[string]string{}
+	for _, build := range manifest.Builds {
+		if build.PackerRunUUID != uuid {
+			continue
+		}
+
+		// The run could have other builders like docker, or post-processors like manifest
+		if !strings.HasPrefix(build.BuilderType, PACKER_AMAZON_BUILDER_PREFIX) {
+			continue
+		}
+
+		// artifact_id is like `ap-northeast-2:ami-xxxx,us-east-1:ami-xxxx`
+		for _, artifact := range strings.Split(build.ArtifactId, ",") {
+			kv := strings.SplitN(strings.TrimSpace(artifact), ":", 2)
+			if len(kv) != 2 || !strings.HasPrefix(kv[1], "ami-") {
+				continue
+			}
+			amis[kv[0]] = kv[1]
+		}
+	}
+
+	if len(amis) == 0 {
+		return nil, fmt.Errorf("no AMI exists for build %s in packer manifest : %s", uuid, path)
+	}
+
+	return amis, nil
+}
@@ -0,0 +1,123 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testPackerManifest = `{
+  "builds": [
+    {
+      "name": "hello",
+      "builder_type": "amazon-ebs",
+      "build_time": 1600000000,
+      "artifact_id": "ap-northeast-2:ami-0000000000000old1",
+      "packer_run_uuid": "old-run"
+    },
+    {
+      "name": "hello",
+      "builder_type": "amazon-ebs",
+      "build_time": 1600001000,
+      "artifact_id": "ap-northeast-2:ami-0123456789abcdef0,us-east-1:ami-0fedcba9876543210",
+      "packer_run_uuid": "last-run"
+    },
+    {
+      "name": "hello-docker",
+      "builder_type": "docker",
+      "build_time": 1600001000,
+      "artifact_id": "eu-west-1:ami-0000000000docker",
+      "packer_run_uuid": "last-run"
+    },
+    {
+      "name": "hello-snapshot",
+      "builder_type": "amazon-ebssurrogate",
+      "build_time": 1600001000,
+      "artifact_id": "eu-west-1:snap-0123456789abcdef0",
+      "packer_run_uuid": "last-run"
+    },
+    {
+      "name": "hello-docker",
+      "builder_type": "docker",
+      "build_time": 1600002000,
+      "artifact_id": "sha256:0123456789abcdef",
+      "packer_run_uuid": "docker-run"
+    }
+  ],
+  "last_run_uuid": "last-run"
+}`
+
+// writeTestFile writes content to the file in temporary directory
+func writeTestFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "goployer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestParsePackerManifest(t *testing.T) {
+	path := writeTestFile(t, "manifest.json", testPackerManifest)
+
+	tests := []struct {
+		name     string
+		uuid     string
+		expected map[string]string
+		err      bool
+	}{
+		{
+			name: "last run with multi-region artifact",
+			uuid: "",
+			expected: map[string]string{
+				"ap-northeast-2": "ami-0123456789abcdef0",
+				"us-east-1":      "ami-0fedcba9876543210",
+			},
+		},
+		{
+			name:     "selected run",
+			uuid:     "old-run",
+			expected: map[string]string{"ap-northeast-2": "ami-0000000000000old1"},
+		},
+		{name: "run without amazon builder", uuid: "docker-run", err: true},
+		{name: "unknown run", uuid: "no-run", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amis, err := ParsePackerManifest(path, tt.uuid)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, but got %v", amis)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(amis, tt.expected) {
+				t.Errorf("expected %v, but got %v", tt.expected, amis)
+			}
+		})
+	}
+}
+
+func TestParsePackerManifestInvalid(t *testing.T) {
+	if _, err := ParsePackerManifest(writeTestFile(t, "manifest.json", "{"), ""); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+
+	if _, err := ParsePackerManifest(filepath.Join(os.TempDir(), "goployer-no-manifest.json"), ""); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,228 @@
+package builder
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"sort"
+	"strings"
+)
+
+var (
+	SIMPLE_SCALING                      = "SimpleScaling"
+	STEP_SCALING                        = "StepScaling"
+	TARGET_TRACKING_SCALING             = "TargetTrackingScaling"
+	ALB_REQUEST_COUNT_PER_TARGET        = "ALBRequestCountPerTarget"
+	availablePolicyTypes                = []string{SIMPLE_SCALING, STEP_SCALING, TARGET_TRACKING_SCALING}
+	availableAdjustmentTypes            = []string{"ChangeInCapacity", "ExactCapacity", "PercentChangeInCapacity"}
+	availableMetricAggregationTypes     = []string{"Average", "Minimum", "Maximum"}
+	availablePredefinedMetrics          = []string{"ASGAverageCPUUtilization", "ASGAverageNetworkIn", "ASGAverageNetworkOut", ALB_REQUEST_COUNT_PER_TARGET}
+	availableCustomizedMetricStatistics = []string{"Average", "Minimum", "Maximum", "SampleCount", "Sum"}
+)
+
+// StepAdjustment is the step of step scaling policy
+// Bounds are relative to the threshold of the alarm, and empty bound means infinity.
+type StepAdjustment struct {
+	LowerBound        *float64 `yaml:"lower_bound" description:"Lower bound of the step relative to the alarm threshold. Empty means negative infinity."`
+	UpperBound        *float64 `yaml:"upper_bound" description:"Upper bound of the step relative to the alarm threshold. Empty means infinity."`
+	ScalingAdjustment int64    `yaml:"scaling_adjustment" description:"Number of instances or percentage to scale by in the step."`
+}
+
+type TargetTrackingConfiguration struct {
+	PredefinedMetric string           `yaml:"predefined_metric" description:"Predefined metric to track, e.g. 'ASGAverageCPUUtilization' or 'ALBRequestCountPerTarget'."`
+	ResourceLabel    string           `yaml:"resource_label" description:"Resource label of ALBRequestCountPerTarget. It is found from the target group of the region if empty."`
+	CustomizedMetric CustomizedMetric `yaml:"customized_metric" description:"CloudWatch metric to track instead of a predefined metric."`
+	TargetValue      float64          `yaml:"target_value" description:"Value of the metric which the policy keeps."`
+	DisableScaleIn   bool             `yaml:"disable_scale_in" description:"Whether the policy only scales out."`
+}
+
+type CustomizedMetric struct {
+	Namespace  string   `yaml:"namespace" description:"Namespace of the metric."`
+	Metric     string   `yaml:"metric" description:"Name of the metric."`
+	Statistic  string   `yaml:"statistic" description:"Statistic of the metric, e.g. 'Average' or 'Sum'."`
+	Unit       string   `yaml:"unit" description:"Unit of the metric."`
+	Dimensions []string `yaml:"dimensions" description:"Dimensions of the metric in 'name=value' format."`
+}
+
+// GetPolicyType returns type of the scaling policy
+func GetPolicyType(policy ScalePolicy) string {
+	if len(policy.PolicyType) == 0 {
+		return SIMPLE_SCALING
+	}
+	return policy.PolicyType
+}
+
+// NeedResourceLabel checks if resource label of target group should be found for the policy
+func NeedResourceLabel(policy ScalePolicy) bool {
+	return GetPolicyType(policy) == TARGET_TRACKING_SCALING && policy.TargetTracking.PredefinedMetric == ALB_REQUEST_COUNT_PER_TARGET && len(policy.TargetTracking.ResourceLabel) == 0
+}
+
+// checkScalePolicies checks validation of scaling policies
+func checkScalePolicies(policies []ScalePolicy, alarms []AlarmConfigs) error {
+	targetTrackings := []string{}
+	for _, policy := range policies {
+		if len(policy.Name) == 0 {
+			return fmt.Errorf("autoscaling policy doesn't have a name.")
+		}
+
+		policyType := GetPolicyType(policy)
+		if !tool.IsStringInArray(policyType, availablePolicyTypes) {
+			return fmt.Errorf("policy_type should be one of %v : %s", availablePolicyTypes, policy.Name)
+		}
+
+		var err error
+		switch policyType {
+		case SIMPLE_SCALING:
+			err = checkSimpleScaling(policy)
+		case STEP_SCALING:
+			err = checkStepScaling(policy)
+		case TARGET_TRACKING_SCALING:
+			err = checkTargetTracking(policy)
+			targetTrackings = append(targetTrackings, policy.Name)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s : %s", err.Error(), policy.Name)
+		}
+	}
+
+	// Target tracking policy manages its own alarms
+	for _, alarm := range alarms {
+		for _, action := range alarm.AlarmActions {
+			if tool.IsStringInArray(action, targetTrackings) {
+				return fmt.Errorf("target tracking policy cannot be used in alarm_actions : %s", action)
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkSimpleScaling(policy ScalePolicy) error {
+	if !tool.IsStringInArray(policy.AdjustmentType, availableAdjustmentTypes) {
+		return fmt.Errorf("adjustment_type should be one of %v", availableAdjustmentTypes)
+	}
+
+	if len(policy.StepAdjustments) > 0 || !tool.IsZero(policy.TargetTracking) {
+		return fmt.Errorf("step_adjustments and target_tracking are not allowed for %s", SIMPLE_SCALING)
+	}
+
+	return nil
+}
+
+func checkStepScaling(policy ScalePolicy) error {
+	if !tool.IsStringInArray(policy.AdjustmentType, availableAdjustmentTypes) {
+		return fmt.Errorf("adjustment_type should be one of %v", availableAdjustmentTypes)
+	}
+
+	if len(policy.MetricAggregationType) > 0 && !tool.IsStringInArray(policy.MetricAggregationType, availableMetricAggregationTypes) {
+		return fmt.Errorf("metric_aggregation_type should be one of %v", availableMetricAggregationTypes)
+	}
+
+	if policy.Cooldown != 0 || policy.ScalingAdjustment != 0 || !tool.IsZero(policy.TargetTracking) {
+		return fmt.Errorf("cooldown, scaling_adjustment and target_tracking are not allowed for %s", STEP_SCALING)
+	}
+
+	if policy.MinAdjustmentMagnitude != 0 && policy.AdjustmentType != "PercentChangeInCapacity" {
+		return fmt.Errorf("min_adjustment_magnitude is only for PercentChangeInCapacity")
+	}
+
+	if len(policy.StepAdjustments) == 0 {
+		return fmt.Errorf("%s needs at least one step adjustment", STEP_SCALING)
+	}
+
+	noLower, noUpper := 0, 0
+	for _, step := range policy.StepAdjustments {
+		if step.LowerBound == nil {
+			noLower++
+		}
+
+		if step.UpperBound == nil {
+			noUpper++
+		}
+
+		if step.LowerBound == nil && step.UpperBound == nil {
+			return fmt.Errorf("step adjustment needs at least one of lower_bound and upper_bound")
+		}
+
+		if step.LowerBound != nil && step.UpperBound != nil && *step.LowerBound >= *step.UpperBound {
+			return fmt.Errorf("upper_bound should be larger than lower_bound of step adjustment : %v >= %v", *step.LowerBound, *step.UpperBound)
+		}
+	}
+
+	if noLower > 1 || noUpper > 1 {
+		return fmt.Errorf("only one step adjustment can have empty lower_bound or upper_bound")
+	}
+
+	// Ranges of steps should neither overlap nor have a gap between them
+	steps := make([]StepAdjustment, len(policy.StepAdjustments))
+	copy(steps, policy.StepAdjustments)
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].LowerBound == nil || (steps[j].LowerBound != nil && *steps[i].LowerBound < *steps[j].LowerBound)
+	})
+
+	for i := 1; i < len(steps); i++ {
+		prev, next := steps[i-1], steps[i]
+		if prev.UpperBound == nil || next.LowerBound == nil || *next.LowerBound < *prev.UpperBound {
+			return fmt.Errorf("ranges of step adjustments should not overlap")
+		}
+
+		if *next.LowerBound > *prev.UpperBound {
+			return fmt.Errorf("ranges of step adjustments should not have a gap : %v ~ %v", *prev.UpperBound, *next.LowerBound)
+		}
+	}
+
+	return nil
+}
+
+func checkTargetTracking(policy ScalePolicy) error {
+	t := policy.TargetTracking
+	if len(policy.AdjustmentType) > 0 || policy.ScalingAdjustment != 0 || policy.Cooldown != 0 || len(policy.StepAdjustments) > 0 {
+		return fmt.Errorf("adjustment_type, scaling_adjustment, cooldown and step_adjustments are not allowed for %s", TARGET_TRACKING_SCALING)
+	}
+
+	if t.TargetValue <= 0 {
+		return fmt.Errorf("target_value of target tracking should be larger than 0")
+	}
+
+	customized := !tool.IsZero(t.CustomizedMetric)
+	if (len(t.PredefinedMetric) > 0) == customized {
+		return fmt.Errorf("target tracking needs either predefined_metric or customized_metric")
+	}
+
+	if len(t.PredefinedMetric) > 0 && !tool.IsStringInArray(t.PredefinedMetric, availablePredefinedMetrics) {
+		return fmt.Errorf("predefined_metric should be one of %v", availablePredefinedMetrics)
+	}
+
+	if len(t.ResourceLabel) > 0 && t.PredefinedMetric != ALB_REQUEST_COUNT_PER_TARGET {
+		return fmt.Errorf("resource_label is only for %s", ALB_REQUEST_COUNT_PER_TARGET)
+	}
+
+	if customized {
+		if len(t.CustomizedMetric.Namespace) == 0 || len(t.CustomizedMetric.Metric) == 0 {
+			return fmt.Errorf("namespace and metric are required for customized_metric")
+		}
+
+		if !tool.IsStringInArray(t.CustomizedMetric.Statistic, availableCustomizedMetricStatistics) {
+			return fmt.Errorf("statistic of customized_metric should be one of %v", availableCustomizedMetricStatistics)
+		}
+
+		for _, dimension := range t.CustomizedMetric.Dimensions {
+			if len(strings.Split(dimension, "=")) != 2 {
+				return fmt.Errorf("dimension of customized_metric should be like key=value : %s", dimension)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkScalePolicyTargets checks if the region has target group for ALBRequestCountPerTarget
+func checkScalePolicyTargets(policies []ScalePolicy, region RegionConfig) error {
+	for _, policy := range policies {
+		if NeedResourceLabel(policy) && len(GetPrimaryTargetGroup(region)) == 0 {
+			return fmt.Errorf("no target group exists for %s of policy %s", ALB_REQUEST_COUNT_PER_TARGET, policy.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package builder
+
+import (
+	"strings"
+	"testing"
+)
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+func TestCheckStepScaling(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []StepAdjustment
+		err   string
+	}{
+		{
+			name: "contiguous steps",
+			steps: []StepAdjustment{
+				{LowerBound: bound(20), ScalingAdjustment: 2},
+				{LowerBound: bound(0), UpperBound: bound(20), ScalingAdjustment: 1},
+			},
+		},
+		{
+			name: "scale in and out around threshold",
+			steps: []StepAdjustment{
+				{UpperBound: bound(0), ScalingAdjustment: -1},
+				{LowerBound: bound(0), ScalingAdjustment: 1},
+			},
+		},
+		{name: "no step", steps: []StepAdjustment{}, err: "at least one step"},
+		{
+			name:  "step without bounds",
+			steps: []StepAdjustment{{ScalingAdjustment: 1}},
+			err:   "at least one of lower_bound and upper_bound",
+		},
+		{
+			name:  "inverted bounds",
+			steps: []StepAdjustment{{LowerBound: bound(20), UpperBound: bound(10), ScalingAdjustment: 1}},
+			err:   "should be larger than lower_bound",
+		},
+		{
+			name: "two open upper bounds",
+			steps: []StepAdjustment{
+				{LowerBound: bound(0), ScalingAdjustment: 1},
+				{LowerBound: bound(20), ScalingAdjustment: 2},
+			},
+			err: "only one step adjustment",
+		},
+		{
+			name: "overlapping steps",
+			steps: []StepAdjustment{
+				{LowerBound: bound(0), UpperBound: bound(30), ScalingAdjustment: 1},
+				{LowerBound: bound(20), ScalingAdjustment: 2},
+			},
+			err: "should not overlap",
+		},
+		{
+			name: "open lower bound overlaps",
+			steps: []StepAdjustment{
+				{UpperBound: bound(10), ScalingAdjustment: -1},
+				{LowerBound: bound(0), UpperBound: bound(20), ScalingAdjustment: 1},
+			},
+			err: "should not overlap",
+		},
+		{
+			name: "gap between steps",
+			steps: []StepAdjustment{
+				{LowerBound: bound(0), UpperBound: bound(10), ScalingAdjustment: 1},
+				{LowerBound: bound(20), ScalingAdjustment: 2},
+			},
+			err: "should not have a gap",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := ScalePolicy{
+				Name:            "scale-out",
+				PolicyType:      STEP_SCALING,
+				AdjustmentType:  "ChangeInCapacity",
+				StepAdjustments: tt.steps,
+			}
+
+			checkError(t, checkStepScaling(policy), tt.err)
+		})
+	}
+}
+
+func TestCheckTargetTracking(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ScalePolicy
+		err    string
+	}{
+		{
+			name:   "predefined metric",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{PredefinedMetric: "ASGAverageCPUUtilization", TargetValue: 50}},
+		},
+		{
+			name: "customized metric",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{
+				TargetValue:      100,
+				CustomizedMetric: CustomizedMetric{Namespace: "hello", Metric: "QueueDepth", Statistic: "Average", Dimensions: []string{"queue=jobs"}},
+			}},
+		},
+		{
+			name:   "scaling adjustment is not allowed",
+			policy: ScalePolicy{ScalingAdjustment: 1, TargetTracking: TargetTrackingConfiguration{PredefinedMetric: "ASGAverageCPUUtilization", TargetValue: 50}},
+			err:    "are not allowed",
+		},
+		{
+			name:   "zero target value",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{PredefinedMetric: "ASGAverageCPUUtilization"}},
+			err:    "target_value",
+		},
+		{
+			name:   "no metric",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{TargetValue: 50}},
+			err:    "either predefined_metric or customized_metric",
+		},
+		{
+			name: "both metrics",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{
+				PredefinedMetric: "ASGAverageCPUUtilization",
+				TargetValue:      50,
+				CustomizedMetric: CustomizedMetric{Namespace: "hello", Metric: "QueueDepth", Statistic: "Average"},
+			}},
+			err: "either predefined_metric or customized_metric",
+		},
+		{
+			name:   "unknown predefined metric",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{PredefinedMetric: "ASGAverageMemoryUtilization", TargetValue: 50}},
+			err:    "predefined_metric should be one of",
+		},
+		{
+			name:   "resource label with other metric",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{PredefinedMetric: "ASGAverageCPUUtilization", ResourceLabel: "app/hello/xxx", TargetValue: 50}},
+			err:    "resource_label is only for",
+		},
+		{
+			name: "customized metric without namespace",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{
+				TargetValue:      100,
+				CustomizedMetric: CustomizedMetric{Metric: "QueueDepth", Statistic: "Average"},
+			}},
+			err: "namespace and metric are required",
+		},
+		{
+			name: "invalid statistic",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{
+				TargetValue:      100,
+				CustomizedMetric: CustomizedMetric{Namespace: "hello", Metric: "QueueDepth", Statistic: "p99"},
+			}},
+			err: "statistic of customized_metric",
+		},
+		{
+			name: "invalid dimension",
+			policy: ScalePolicy{TargetTracking: TargetTrackingConfiguration{
+				TargetValue:      100,
+				CustomizedMetric: CustomizedMetric{Namespace: "hello", Metric: "QueueDepth", Statistic: "Average", Dimensions: []string{"queue"}},
+			}},
+			err: "dimension of customized_metric",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.Name = "track"
+			tt.policy.PolicyType = TARGET_TRACKING_SCALING
+
+			checkError(t, checkTargetTracking(tt.policy), tt.err)
+		})
+	}
+}
+
+func TestCheckScalePoliciesTargetTrackingInAlarm(t *testing.T) {
+	policies := []ScalePolicy{
+		{
+			Name:           "track",
+			PolicyType:     TARGET_TRACKING_SCALING,
+			TargetTracking: TargetTrackingConfiguration{PredefinedMetric: "ASGAverageCPUUtilization", TargetValue: 50},
+		},
+	}
+	alarms := []AlarmConfigs{{Name: "cpu-high", AlarmActions: []string{"track"}}}
+
+	checkError(t, checkScalePolicies(policies, nil), "")
+	checkError(t, checkScalePolicies(policies, alarms), "cannot be used in alarm_actions")
+}
+
+// checkError checks that err contains expected message, or err is nil if expected is empty
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+
+	if len(expected) == 0 {
+		if err != nil {
+			t.Errorf("expected no error, but got %s", err.Error())
+		}
+		return
+	}
+
+	if err == nil {
+		t.Errorf("expected error with %q, but got nil", expected)
+		return
+	}
+
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error with %q, but got %s", expected, err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ScheduledAction struct {
+	Name       string `yaml:"name" description:"Name of the scheduled action."`
+	Recurrence string `yaml:"recurrence" description:"Cron expression with 5 fields."`
+	StartTime  string `yaml:"start_time" description:"Time when the action starts in RFC3339 format."`
+	EndTime    string `yaml:"end_time" description:"Time when the recurrence ends in RFC3339 format."`
+	TimeZone   string `yaml:"time_zone" description:"Time zone of recurrence, e.g. Asia/Seoul."`
+	Min        *int64 `yaml:"min" description:"Minimum size of autoscaling group at the schedule."`
+	Max        *int64 `yaml:"max" description:"Maximum size of autoscaling group at the schedule."`
+	Desired    *int64 `yaml:"desired" description:"Desired capacity of autoscaling group at the schedule."`
+}
+
+// Summary returns short description of scheduled action
+func (s ScheduledAction) Summary() string {
+	capacity := []string{}
+	if s.Min != nil {
+		capacity = append(capacity, fmt.Sprintf("min=%d", *s.Min))
+	}
+	if s.Max != nil {
+		capacity = append(capacity, fmt.Sprintf("max=%d", *s.Max))
+	}
+	if s.Desired != nil {
+		capacity = append(capacity, fmt.Sprintf("desired=%d", *s.Desired))
+	}
+
+	schedule := s.Recurrence
+	if len(schedule) == 0 {
+		schedule = s.StartTime
+	}
+
+	return fmt.Sprintf("%s(%s %s, %s)", s.Name, schedule, s.TimeZone, strings.Join(capacity, " "))
+}
+
+// IsExpired returns true if the action would never run again after now
+// AWS rejects one-off action whose start_time is in the past, and action whose end_time is in the past.
+func (s ScheduledAction) IsExpired(now time.Time) bool {
+	if len(s.Recurrence) == 0 && len(s.StartTime) > 0 {
+		if start, err := time.Parse(time.RFC3339, s.StartTime); err == nil && !start.After(now) {
+			return true
+		}
+	}
+
+	if len(s.EndTime) > 0 {
+		if end, err := time.Parse(time.RFC3339, s.EndTime); err == nil && !end.After(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkScheduledActions checks validation of scheduled actions
+func checkScheduledActions(actions []ScheduledAction) error {
+	names := []string{}
+	for _, action := range actions {
+		if len(action.Name) == 0 {
+			return fmt.Errorf("scheduled action doesn't have a name.")
+		}
+
+		for _, name := range names {
+			if name == action.Name {
+				return fmt.Errorf("names of scheduled actions are duplicated : %s", action.Name)
+			}
+		}
+		names = append(names, action.Name)
+
+		if action.Min == nil && action.Max == nil && action.Desired == nil {
+			return fmt.Errorf("scheduled action needs at least one of min, max and desired : %s", action.Name)
+		}
+
+		if len(action.Recurrence) == 0 && len(action.StartTime) == 0 {
+			return fmt.Errorf("scheduled action needs recurrence or start_time : %s", action.Name)
+		}
+
+		if len(action.Recurrence) > 0 && len(strings.Fields(action.Recurrence)) != 5 {
+			return fmt.Errorf("recurrence of scheduled action should be cron expression with 5 fields : %s", action.Name)
+		}
+
+		for _, t := range []string{action.StartTime, action.EndTime} {
+			if len(t) == 0 {
+				continue
+			}
+
+			if _, err := time.Parse(time.RFC3339, t); err != nil {
+				return fmt.Errorf("start_time and end_time of scheduled action should be RFC3339 format like 2020-01-01T09:00:00Z : %s", action.Name)
+			}
+		}
+
+		if len(action.TimeZone) > 0 {
+			if _, err := time.LoadLocation(action.TimeZone); err != nil {
+				return fmt.Errorf("time_zone of scheduled action is not valid : %s", action.TimeZone)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package builder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckScheduledActions(t *testing.T) {
+	one := int64(1)
+
+	tests := []struct {
+		name    string
+		actions []ScheduledAction
+		err     string
+	}{
+		{
+			name: "recurrence with time zone",
+			actions: []ScheduledAction{
+				{Name: "morning", Recurrence: "0 8 * * 1-5", TimeZone: "Asia/Seoul", Desired: &one},
+				{Name: "night", Recurrence: "0 20 * * 1-5", EndTime: "2030-12-31T00:00:00Z", Min: &one},
+			},
+		},
+		{
+			name:    "one-off action",
+			actions: []ScheduledAction{{Name: "launch", StartTime: "2030-01-01T09:00:00Z", Max: &one}},
+		},
+		{
+			name:    "no name",
+			actions: []ScheduledAction{{Recurrence: "0 8 * * *", Desired: &one}},
+			err:     "doesn't have a name",
+		},
+		{
+			name: "duplicated names",
+			actions: []ScheduledAction{
+				{Name: "morning", Recurrence: "0 8 * * *", Desired: &one},
+				{Name: "morning", Recurrence: "0 9 * * *", Desired: &one},
+			},
+			err: "duplicated",
+		},
+		{
+			name:    "no capacity",
+			actions: []ScheduledAction{{Name: "morning", Recurrence: "0 8 * * *"}},
+			err:     "at least one of min, max and desired",
+		},
+		{
+			name:    "no schedule",
+			actions: []ScheduledAction{{Name: "morning", Desired: &one}},
+			err:     "recurrence or start_time",
+		},
+		{
+			name:    "recurrence with 6 fields",
+			actions: []ScheduledAction{{Name: "morning", Recurrence: "0 0 8 * * *", Desired: &one}},
+			err:     "5 fields",
+		},
+		{
+			name:    "start time without time zone",
+			actions: []ScheduledAction{{Name: "launch", StartTime: "2030-01-01 09:00:00", Desired: &one}},
+			err:     "RFC3339",
+		},
+		{
+			name:    "unknown time zone",
+			actions: []ScheduledAction{{Name: "morning", Recurrence: "0 8 * * *", TimeZone: "Mars/Olympus", Desired: &one}},
+			err:     "time_zone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkError(t, checkScheduledActions(tt.actions), tt.err)
+		})
+	}
+}
+
+func TestScheduledActionIsExpired(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		action  ScheduledAction
+		expired bool
+	}{
+		{name: "recurrence", action: ScheduledAction{Recurrence: "0 8 * * *"}, expired: false},
+		{name: "recurrence started in the past", action: ScheduledAction{Recurrence: "0 8 * * *", StartTime: "2025-01-01T00:00:00Z"}, expired: false},
+		{name: "recurrence ended in the past", action: ScheduledAction{Recurrence: "0 8 * * *", EndTime: "2025-12-31T00:00:00Z"}, expired: true},
+		{name: "one-off at the moment in other offset", action: ScheduledAction{StartTime: "2026-01-01T09:00:00+09:00"}, expired: true},
+		{name: "one-off in the past", action: ScheduledAction{StartTime: "2025-12-31T23:00:00Z"}, expired: true},
+		{name: "one-off later today", action: ScheduledAction{StartTime: "2026-01-01T09:00:00Z"}, expired: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.action.IsExpired(now) != tt.expired {
+				t.Errorf("expected expired=%t for %+v", tt.expired, tt.action)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package builder
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var (
+	JSON_SCHEMA_VERSION = "http://json-schema.org/draft-07/schema#"
+	DEFINITION_PREFIX   = "#/definitions/"
+)
+
+type Schema struct {
+	Schema      string                 `json:"$schema"`
+	Ref         string                 `json:"$ref"`
+	Definitions map[string]*Definition `json:"definitions"`
+}
+
+type Definition struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Items                *Definition            `json:"items,omitempty"`
+	AllOf                []*Definition          `json:"allOf,omitempty"`
+	Properties           map[string]*Definition `json:"properties,omitempty"`
+	PreferredOrder       []string               `json:"preferredOrder,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+}
+
+// GenerateSchema generates JSON schema of the manifest file from the builder types
+func GenerateSchema() ([]byte, error) {
+	schema := Schema{
+		Schema:      JSON_SCHEMA_VERSION,
+		Definitions: map[string]*Definition{},
+	}
+
+	schema.Ref = newDefinition(reflect.TypeOf(YamlConfig{}), schema.Definitions).Ref
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// newDefinition returns the definition of the type
+// Structs are registered in definitions and referred by $ref.
+func newDefinition(t reflect.Type, definitions map[string]*Definition) *Definition {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return newDefinition(t.Elem(), definitions)
+	case reflect.Bool:
+		return &Definition{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Definition{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Definition{Type: "number"}
+	case reflect.String:
+		return &Definition{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Definition{Type: "array", Items: newDefinition(t.Elem(), definitions)}
+	case reflect.Map:
+		return &Definition{Type: "object", AdditionalProperties: newDefinition(t.Elem(), definitions)}
+	case reflect.Struct:
+		ref := &Definition{Ref: DEFINITION_PREFIX + t.Name()}
+		if _, ok := definitions[t.Name()]; ok {
+			return ref
+		}
+
+		def := &Definition{
+			Type:                 "object",
+			Properties:           map[string]*Definition{},
+			AdditionalProperties: false,
+		}
+
+		// Register before visiting fields for recursive types
+		definitions[t.Name()] = def
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, ok := yamlFieldName(field)
+			if !ok {
+				continue
+			}
+
+			prop := newDefinition(field.Type, definitions)
+			if description := field.Tag.Get("description"); len(description) > 0 {
+				// $ref ignores sibling keywords, so the reference is wrapped with allOf
+				prop = withDescription(prop, description)
+			}
+
+			def.Properties[name] = prop
+			def.PreferredOrder = append(def.PreferredOrder, name)
+			if field.Tag.Get("required") == "true" {
+				def.Required = append(def.Required, name)
+			}
+		}
+
+		return ref
+	}
+
+	return &Definition{}
+}
+
+// withDescription adds the description of the field to the definition
+func withDescription(def *Definition, description string) *Definition {
+	if len(def.Ref) == 0 {
+		def.Description = description
+		return def
+	}
+
+	return &Definition{AllOf: []*Definition{def}, Description: description}
+}
+
+// yamlFieldName returns the key of the field in yaml
+func yamlFieldName(field reflect.StructField) (string, bool) {
+	if len(field.PkgPath) > 0 {
+		return "", false
+	}
+
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if tag == "-" {
+		return "", false
+	}
+
+	if len(tag) == 0 {
+		return strings.ToLower(field.Name), true
+	}
+
+	return tag, true
+}
@@ -0,0 +1,60 @@
+package builder
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+)
+
+var (
+	WARM_POOL_STATE_STOPPED    = "Stopped"
+	WARM_POOL_STATE_RUNNING    = "Running"
+	WARM_POOL_STATE_HIBERNATED = "Hibernated"
+
+	availableWarmPoolStates = []string{WARM_POOL_STATE_STOPPED, WARM_POOL_STATE_RUNNING, WARM_POOL_STATE_HIBERNATED}
+)
+
+type WarmPool struct {
+	PoolState           string `yaml:"pool_state" description:"State of instances in the warm pool, 'Stopped'(default), 'Running' or 'Hibernated'."`
+	MinSize             int64  `yaml:"min_size" description:"Minimum number of instances in the warm pool."`
+	MaxPreparedCapacity int64  `yaml:"max_prepared_capacity" description:"Maximum number of instances in the warm pool and autoscaling group. (default: max size)"`
+	ReuseOnScaleIn      bool   `yaml:"reuse_on_scale_in" description:"Whether instances return to the warm pool on scale in."`
+}
+
+// GetWarmPoolState returns state of instances in warm pool
+func GetWarmPoolState(warmPool WarmPool) string {
+	if len(warmPool.PoolState) == 0 {
+		return WARM_POOL_STATE_STOPPED
+	}
+	return warmPool.PoolState
+}
+
+// checkWarmPool checks settings of warm pool
+// Warm pool cannot be used with spot instances or mixed instances policy.
+func checkWarmPool(stack Stack) error {
+	warmPool := stack.WarmPool
+	if tool.IsZero(warmPool) {
+		return nil
+	}
+
+	if len(warmPool.PoolState) > 0 && !tool.IsStringInArray(warmPool.PoolState, availableWarmPoolStates) {
+		return fmt.Errorf("pool_state of warm_pool should be one of %v : %s", availableWarmPoolStates, warmPool.PoolState)
+	}
+
+	if warmPool.MinSize < 0 {
+		return fmt.Errorf("min_size of warm_pool cannot be negative : %d", warmPool.MinSize)
+	}
+
+	if warmPool.MaxPreparedCapacity < 0 {
+		return fmt.Errorf("max_prepared_capacity of warm_pool cannot be negative : %d", warmPool.MaxPreparedCapacity)
+	}
+
+	if warmPool.MaxPreparedCapacity > 0 && warmPool.MaxPreparedCapacity < warmPool.MinSize {
+		return fmt.Errorf("max_prepared_capacity of warm_pool cannot be smaller than min_size : %d", warmPool.MaxPreparedCapacity)
+	}
+
+	if len(stack.InstanceMarketOptions.MarketType) > 0 || stack.MixedInstancesPolicy.Enabled {
+		return fmt.Errorf("warm_pool cannot be used with spot instances or mixed_instances_policy")
+	}
+
+	return nil
+}
@@ -1,12 +1,15 @@
 package deployer
 
 import (
+	"errors"
 	"fmt"
 	"github.com/DevopsArtFactory/goployer/pkg/aws"
 	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/healthchecker"
 	"github.com/DevopsArtFactory/goployer/pkg/tool"
 	Logger "github.com/sirupsen/logrus"
 	"strings"
+	"time"
 )
 
 type BlueGreen struct {
@@ -20,14 +23,24 @@ func NewBlueGrean(mode string, logger *Logger.Logger, awsConfig builder.AWSConfi
 	}
 	return BlueGreen{
 		Deployer{
-			Mode:          mode,
-			Logger:        logger,
-			AwsConfig:     awsConfig,
-			AWSClients:    awsClients,
-			AsgNames:      map[string]string{},
-			PrevAsgs:      map[string][]string{},
-			PrevInstances: map[string][]string{},
-			Stack:         stack,
+			Mode:              mode,
+			Logger:            logger,
+			AwsConfig:         awsConfig,
+			AWSClients:        awsClients,
+			AsgNames:          map[string]string{},
+			PrevAsgs:          map[string][]string{},
+			PrevInstances:     map[string][]string{},
+			Stack:             stack,
+			HealthCheckers:    map[string]healthchecker.HealthChecker{},
+			GateStartTimes:    map[string]time.Time{},
+			HealthySuccesses:  map[string]int64{},
+			PhaseStartTimes:   map[string]time.Time{},
+			Polls:             map[string]int{},
+			SeenActivities:    map[string]bool{},
+			DrainStartTimes:   map[string]time.Time{},
+			ReadinessCheckers: map[string]healthchecker.ReadinessChecker{},
+			CompletedHooks:    map[string]bool{},
+			Amis:              map[string]string{},
 		},
 	}
 }
@@ -49,6 +62,14 @@ func (b BlueGreen) Deploy(config builder.Config) {
 			continue
 		}
 
+		// Deployment of each region should start within timeout of deploy phase
+		if err := b.CheckPhaseTimeout(builder.PHASE_DEPLOY); err != nil {
+			tool.ErrorLogging(err.Error())
+		}
+
+		// Get effective stack configuration of the region
+		stack := builder.ApplyRegionOverrides(b.Stack, region)
+
 		//Setup frigga with prefix
 		frigga.Prefix = tool.BuildPrefixName(b.AwsConfig.Name, b.Stack.Env, region.Region)
 
@@ -78,17 +99,34 @@ func (b BlueGreen) Deploy(config builder.Config) {
 			prevInstanceCount.Min = *asgGroup.MinSize
 		}
 		b.Logger.Info("Previous Versions : ", strings.Join(prevAsgs, " | "))
+		b.PrevAsgs[region.Region] = prevAsgs
+		b.PrevInstances[region.Region] = prevInstanceIds
+
+		if err := b.RunCallbacks(client, region.Region, builder.CALLBACK_PHASE_PRE_DEPLOY); err != nil {
+			tool.ErrorLogging(err.Error())
+		}
+
+		//Previous autoscaling groups should not scale while deployment is in flight.
+		for _, asg := range prevAsgs {
+			if err := b.suspendProcesses(client, asg); err != nil {
+				tool.ErrorLogging(err.Error())
+			}
+		}
 
 		// Get Current Version
 		curVersion := getCurrentVersion(prevVersions)
 		b.Logger.Info("Current Version :", curVersion)
 
 		//Get AMI
-		var ami string
-		if len(config.Ami) > 0 {
-			ami = config.Ami
-		} else {
-			ami = region.AmiId
+		ami, err := b.getAmi(config, region, client)
+		if err != nil {
+			tool.ErrorLogging(err.Error())
+		}
+		b.Logger.Infof("AMI for %s : %s", region.Region, ami)
+		b.Amis[region.Region] = ami
+
+		if err := b.RunLambdaHooks(client, config, region.Region, builder.CALLBACK_PHASE_PRE_DEPLOY); err != nil {
+			tool.ErrorLogging(err.Error())
 		}
 
 		// Generate new name for autoscaling group and launch configuration
@@ -99,15 +137,15 @@ func (b BlueGreen) Deploy(config builder.Config) {
 
 		//Stack check
 		securityGroups := client.EC2Service.GetSecurityGroupList(region.VPC, region.SecurityGroups)
-		blockDevices := client.EC2Service.MakeLaunchTemplateBlockDeviceMappings(b.Stack.BlockDevices)
-		ebsOptimized := b.Stack.EbsOptimized
+		blockDevices := client.EC2Service.MakeLaunchTemplateBlockDeviceMappings(stack.BlockDevices)
+		ebsOptimized := stack.EbsOptimized
 
 		// Instance Type Override
 		instanceType := region.InstanceType
 		if len(config.OverrideInstanceType) > 0 {
 			instanceType = config.OverrideInstanceType
 
-			if b.Stack.MixedInstancesPolicy.Enabled {
+			if stack.MixedInstancesPolicy.Enabled {
 				Logger.Warnf("--override-instance-type won't be applied because mixed_instances_policy is enabled")
 			}
 		}
@@ -121,10 +159,10 @@ func (b BlueGreen) Deploy(config builder.Config) {
 			b.Stack.IamInstanceProfile,
 			userdata,
 			ebsOptimized,
-			b.Stack.MixedInstancesPolicy.Enabled,
+			stack.MixedInstancesPolicy.Enabled,
 			securityGroups,
 			blockDevices,
-			b.Stack.InstanceMarketOptions,
+			stack.InstanceMarketOptions,
 		)
 
 		if !ret {
@@ -133,20 +171,20 @@ func (b BlueGreen) Deploy(config builder.Config) {
 
 		healthElb := region.HealthcheckLB
 		loadbalancers := region.LoadBalancers
-		if !tool.IsStringInArray(healthElb, loadbalancers) {
+		if len(healthElb) > 0 && !tool.IsStringInArray(healthElb, loadbalancers) {
 			loadbalancers = append(loadbalancers, healthElb)
 		}
 
 		healthcheckTargetGroups := region.HealthcheckTargetGroup
 		targetGroups := region.TargetGroups
-		if !tool.IsStringInArray(healthcheckTargetGroups, targetGroups) {
+		if len(healthcheckTargetGroups) > 0 && !tool.IsStringInArray(healthcheckTargetGroups, targetGroups) {
 			targetGroups = append(targetGroups, healthcheckTargetGroups)
 		}
 
 		usePublicSubnets := region.UsePublicSubnets
-		healthcheckType := aws.DEFAULT_HEALTHCHECK_TYPE
-		healthcheckGracePeriod := int64(aws.DEFAULT_HEALTHCHECK_GRACE_PERIOD)
-		terminationPolicies := []*string{}
+		healthcheckType := builder.GetHealthCheckType(b.Stack)
+		healthcheckGracePeriod := builder.GetHealthCheckGracePeriod(b.Stack)
+		terminationPolicies := aws.MakeStringArrayToAwsStrings(b.Stack.TerminationPolicies)
 		availabilityZones := client.EC2Service.GetAvailabilityZones(region.VPC, region.AvailabilityZones)
 		targetGroupArns := client.ELBService.GetTargetGroupARNs(targetGroups)
 		tags := client.EC2Service.GenerateTags(b.AwsConfig.Tags, new_asg_name, b.AwsConfig.Name, config.Stack, b.Stack.AnsibleTags, config.ExtraTags, config.AnsibleExtraVars, region.Region)
@@ -154,11 +192,11 @@ func (b BlueGreen) Deploy(config builder.Config) {
 		lifecycleHooksSpecificationList := client.EC2Service.GenerateLifecycleHooks(b.Stack.LifecycleHooks)
 
 		var appliedCapacity builder.Capacity
-		if !config.ForceManifestCapacity && prevInstanceCount.Desired > b.Stack.Capacity.Desired {
+		if !config.ForceManifestCapacity && prevInstanceCount.Desired > stack.Capacity.Desired {
 			appliedCapacity = prevInstanceCount
 			b.Logger.Infof("Current desired instance count is larger than the number of instances in manifest file")
 		} else {
-			appliedCapacity = b.Stack.Capacity
+			appliedCapacity = stack.Capacity
 		}
 
 		b.Logger.Infof("Applied instance capacity - Min: %d, Desired: %d, Max: %d", appliedCapacity.Max, appliedCapacity.Desired, appliedCapacity.Max)
@@ -168,6 +206,9 @@ func (b BlueGreen) Deploy(config builder.Config) {
 			launch_template_name,
 			healthcheckType,
 			healthcheckGracePeriod,
+			b.Stack.DefaultCooldown,
+			b.Stack.MaxInstanceLifetime,
+			b.Stack.CapacityRebalance,
 			appliedCapacity,
 			aws.MakeStringArrayToAwsStrings(loadbalancers),
 			targetGroupArns,
@@ -175,7 +216,7 @@ func (b BlueGreen) Deploy(config builder.Config) {
 			aws.MakeStringArrayToAwsStrings(availabilityZones),
 			tags,
 			subnets,
-			b.Stack.MixedInstancesPolicy,
+			stack.MixedInstancesPolicy,
 			lifecycleHooksSpecificationList,
 		)
 
@@ -183,9 +224,18 @@ func (b BlueGreen) Deploy(config builder.Config) {
 			tool.ErrorLogging("Unknown error happened creating new autoscaling group.")
 		}
 
+		if err := b.suspendProcesses(client, new_asg_name); err != nil {
+			tool.ErrorLogging(err.Error())
+		}
+
+		if !tool.IsZero(b.Stack.WarmPool) {
+			if err := client.EC2Service.PutWarmPool(new_asg_name, b.Stack.WarmPool); err != nil {
+				tool.ErrorLogging(err.Error())
+			}
+		}
+
 		b.AsgNames[region.Region] = new_asg_name
-		b.PrevAsgs[region.Region] = prevAsgs
-		b.PrevInstances[region.Region] = prevInstanceIds
+		b.notify(builder.NOTIFY_EVENT_REGION_DEPLOYED, region.Region, new_asg_name, fmt.Sprintf(":rocket: New autoscaling group is created : %s", new_asg_name))
 
 		if b.Collector.MetricConfig.Enabled {
 			additionalFields := map[string]string{}
@@ -201,14 +251,14 @@ func (b BlueGreen) Deploy(config builder.Config) {
 				additionalFields["userdata"] = userdata
 			}
 
-			b.Stack.Capacity = appliedCapacity
-			b.Collector.StampDeployment(b.Stack, config, tags, new_asg_name, "creating", additionalFields)
+			stack.Capacity = appliedCapacity
+			b.Collector.StampDeployment(stack, config, tags, new_asg_name, "creating", additionalFields)
 		}
 	}
 }
 
 // Healthchecking
-func (b BlueGreen) HealthChecking(config builder.Config) map[string]bool {
+func (b BlueGreen) HealthChecking(config builder.Config) (map[string]bool, error) {
 	stack_name := b.GetStackName()
 	Logger.Debug(fmt.Sprintf("Healthchecking for stack starts : %s", stack_name))
 	finished := []string{}
@@ -241,9 +291,24 @@ func (b BlueGreen) HealthChecking(config builder.Config) map[string]bool {
 			tool.ErrorLogging(err.Error())
 		}
 
+		// Activities are checked again at next poll if they cannot be read
+		failure, err := b.checkLaunchFailures(region, client)
+		if err != nil {
+			b.Logger.Warnf("cannot read scaling activities of %s : %s", b.AsgNames[region.Region], err.Error())
+		} else if len(failure) > 0 {
+			return nil, b.handleFailure(config, b.Stack.Healthcheck.OnFailure, errors.New(failure))
+		}
+
 		asg := client.EC2Service.GetMatchingAutoscalingGroup(b.AsgNames[region.Region])
 
-		isHealthy := b.Deployer.polling(region, asg, client)
+		if err := b.completeLaunchHooks(region, asg, client); err != nil {
+			return nil, b.handleFailure(config, b.Stack.Healthcheck.OnFailure, err)
+		}
+
+		isHealthy, err := b.Deployer.polling(region, asg, client)
+		if err != nil {
+			return nil, b.handleFailure(config, b.Stack.Healthcheck.OnFailure, err)
+		}
 
 		if isHealthy {
 			if b.Collector.MetricConfig.Enabled {
@@ -256,10 +321,54 @@ func (b BlueGreen) HealthChecking(config builder.Config) map[string]bool {
 	}
 
 	if len(finished) == validCount {
-		return map[string]bool{stack_name: true}
+		return map[string]bool{stack_name: true}, nil
 	}
 
-	return map[string]bool{stack_name: false}
+	return map[string]bool{stack_name: false}, nil
+}
+
+// HealthGating checks metrics of new autoscaling groups with health gates
+// If any gate is breached, the deployment fails and is rolled back if `on_failure` is rollback.
+func (b BlueGreen) HealthGating(config builder.Config) (map[string]bool, error) {
+	stack_name := b.GetStackName()
+	if len(b.Stack.HealthGates.Gates) == 0 {
+		return map[string]bool{stack_name: true}, nil
+	}
+
+	Logger.Debug(fmt.Sprintf("Health gating for stack starts : %s", stack_name))
+
+	passed := true
+	for _, region := range b.Stack.Regions {
+		//If region id is passed from command line, then deployer will deploy in that region only.
+		if config.Region != "" && config.Region != region.Region {
+			continue
+		}
+
+		//select client
+		client, err := selectClientFromList(b.AWSClients, region.Region)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := b.checkHealthGates(region, client)
+		if err != nil {
+			return nil, b.handleFailure(config, b.Stack.HealthGates.OnFailure, err)
+		}
+
+		passed = passed && ok
+	}
+
+	return map[string]bool{stack_name: passed}, nil
+}
+
+// RollbackOnFailure rolls back new autoscaling groups when deployment of any stack failed
+// Stacks with fail policy are not rolled back, but they do not proceed either.
+func (b BlueGreen) RollbackOnFailure(config builder.Config) error {
+	if b.Stack.Healthcheck.OnFailure != builder.ON_FAILURE_ROLLBACK && b.Stack.HealthGates.OnFailure != builder.ON_FAILURE_ROLLBACK {
+		return nil
+	}
+
+	return b.rollback(config)
 }
 
 //Stack Name Getter
@@ -269,11 +378,6 @@ func (b BlueGreen) GetStackName() string {
 
 //BlueGreen finish final work
 func (b BlueGreen) FinishAdditionalWork(config builder.Config) error {
-	if len(b.Stack.Autoscaling) == 0 {
-		b.Logger.Debug("No scaling policy exists")
-		return nil
-	}
-
 	if len(config.Region) > 0 && !checkRegionExist(config.Region, b.Stack.Regions) {
 		return nil
 	}
@@ -286,7 +390,7 @@ func (b BlueGreen) FinishAdditionalWork(config builder.Config) error {
 			continue
 		}
 
-		b.Logger.Info("Attaching autoscaling policies : " + region.Region)
+		stack := builder.ApplyRegionOverrides(b.Stack, region)
 
 		//select client
 		client, err := selectClientFromList(b.AWSClients, region.Region)
@@ -294,10 +398,41 @@ func (b BlueGreen) FinishAdditionalWork(config builder.Config) error {
 			tool.ErrorLogging(err.Error())
 		}
 
+		if err := b.resumeProcesses(client, b.AsgNames[region.Region]); err != nil {
+			return err
+		}
+
+		//Scheduled actions are created on new autoscaling group, and deleted with previous one.
+		for _, action := range stack.ScheduledActions {
+			if action.IsExpired(time.Now()) {
+				b.Logger.Warnf("Scheduled action is skipped because its schedule is in the past : %s", action.Summary())
+				continue
+			}
+
+			if err := client.EC2Service.CreateScheduledAction(action, b.AsgNames[region.Region]); err != nil {
+				return err
+			}
+		}
+
+		if len(stack.Autoscaling) == 0 && len(stack.Alarms) == 0 {
+			b.Logger.Debug("No scaling policy exists : " + region.Region)
+			continue
+		}
+
+		b.Logger.Info("Attaching autoscaling policies : " + region.Region)
+
 		//putting autoscaling group policies
 		policies := []string{}
 		policyArns := map[string]string{}
-		for _, policy := range b.Stack.Autoscaling {
+		for _, policy := range stack.Autoscaling {
+			if builder.NeedResourceLabel(policy) {
+				label, err := client.ELBService.GetResourceLabel(builder.GetPrimaryTargetGroup(region))
+				if err != nil {
+					return err
+				}
+				policy.TargetTracking.ResourceLabel = label
+			}
+
 			policyArn, err := client.EC2Service.CreateScalingPolicy(policy, b.AsgNames[region.Region])
 			if err != nil {
 				tool.ErrorLogging(err.Error())
@@ -311,8 +446,14 @@ func (b BlueGreen) FinishAdditionalWork(config builder.Config) error {
 			return err
 		}
 
-		if err := client.CloudWatchService.CreateScalingAlarms(b.AsgNames[region.Region], b.Stack.Alarms, policyArns); err != nil {
-			return nil
+		//Alarms are named after the new autoscaling group and deleted with it.
+		alarms, err := b.getAlarms(stack.Alarms, region, client)
+		if err != nil {
+			return err
+		}
+
+		if err := client.CloudWatchService.CreateScalingAlarms(b.AsgNames[region.Region], alarms, policyArns); err != nil {
+			return err
 		}
 	}
 
@@ -322,8 +463,13 @@ func (b BlueGreen) FinishAdditionalWork(config builder.Config) error {
 
 // Run lifecycle callbacks before cleaninig.
 func (b BlueGreen) TriggerLifecycleCallbacks(config builder.Config) error {
-	if &b.Stack.LifecycleCallbacks == nil || len(b.Stack.LifecycleCallbacks.PreTerminatePastClusters) == 0 {
-		b.Logger.Debugf("no lifecycle callbacks in %s\n", b.Stack.Stack)
+	return b.TriggerCallbacks(config, builder.CALLBACK_PHASE_PRE_TERMINATE)
+}
+
+// TriggerCallbacks runs lifecycle callbacks of the phase in each region
+func (b BlueGreen) TriggerCallbacks(config builder.Config, phase string) error {
+	if len(builder.GetCallbacks(b.Stack.LifecycleCallbacks, phase)) == 0 && len(builder.GetLambdaHooks(b.Stack.LambdaHooks, phase)) == 0 {
+		b.Logger.Debugf("no %s callbacks in %s\n", phase, b.Stack.Stack)
 		return nil
 	}
 
@@ -343,17 +489,16 @@ func (b BlueGreen) TriggerLifecycleCallbacks(config builder.Config) error {
 		//select client
 		client, err := selectClientFromList(b.AWSClients, region.Region)
 		if err != nil {
-			tool.ErrorLogging(err.Error())
+			return err
 		}
 
-		if len(b.PrevInstances[region.Region]) > 0 {
-			b.Deployer.RunLifecycleCallbacks(client, b.PrevInstances[region.Region])
-		} else {
-
-			b.Logger.Infof("No previous versions to be deleted : %s\n", region.Region)
-			b.Slack.SendSimpleMessage(fmt.Sprintf("No previous versions to be deleted : %s\n", region.Region), config.Env)
+		if err := b.Deployer.RunCallbacks(client, region.Region, phase); err != nil {
+			return err
 		}
 
+		if err := b.Deployer.RunLambdaHooks(client, config, region.Region, phase); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -379,21 +524,21 @@ func (b BlueGreen) CleanPreviousVersion(config builder.Config) error {
 		//select client
 		client, err := selectClientFromList(b.AWSClients, region.Region)
 		if err != nil {
-			tool.ErrorLogging(err.Error())
+			return err
 		}
 
 		if len(b.PrevAsgs[region.Region]) > 0 {
 			for _, asg := range b.PrevAsgs[region.Region] {
-				b.Logger.Debugf("[Resizing to 0] target autoscaling group : %s", asg)
-				// First make autoscaling group size to 0
-				err := b.ResizingAutoScalingGroupToZero(client, b.Stack.Stack, asg)
+				b.Logger.Debugf("[Draining] target autoscaling group : %s", asg)
+				// First detach load balancers, and autoscaling group is resized to 0 after draining
+				err := b.DrainAutoScalingGroup(client, b.Stack.Stack, asg)
 				if err != nil {
 					return err
 				}
 			}
 		} else {
 			b.Logger.Infof("No previous versions to be deleted : %s\n", region.Region)
-			b.Slack.SendSimpleMessage(fmt.Sprintf("No previous versions to be deleted : %s\n", region.Region), config.Env)
+			b.notify(builder.NOTIFY_EVENT_CLEANUP, region.Region, "", fmt.Sprintf("No previous versions to be deleted : %s\n", region.Region))
 		}
 	}
 
@@ -0,0 +1,208 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RunCallbacks runs callbacks of the phase in the region
+// Each callback follows its own failure policy.
+func (d Deployer) RunCallbacks(client aws.AWSClient, region, phase string) error {
+	for _, callback := range builder.GetCallbacks(d.Stack.LifecycleCallbacks, phase) {
+		if err := d.runCallback(client, region, phase, callback); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runCallback runs commands of the callback, and waits for the results.
+// If commands fail, it follows the failure policy of the callback.
+func (d Deployer) runCallback(client aws.AWSClient, region, phase string, callback builder.Callback) error {
+	env := d.getCallbackEnvironments(region, phase)
+	timeout := builder.GetCallbackTimeout(callback)
+	attempts := builder.GetCallbackAttempts(callback)
+
+	target := []string{}
+	if callback.Target != builder.CALLBACK_TARGET_LOCAL {
+		target = d.getCallbackTargets(client, region, callback.Target)
+		if len(target) == 0 {
+			d.Logger.Infof("[%s] no target instance exists for callback %s", region, callback.Name)
+			return nil
+		}
+	}
+
+	var err error
+	for attempt := int64(1); attempt <= attempts; attempt++ {
+		d.Logger.Infof("[%s] run %s callback %s(%d/%d)", region, phase, callback.Name, attempt, attempts)
+
+		var failed []string
+		if callback.Target == builder.CALLBACK_TARGET_LOCAL {
+			err = d.runLocalCommands(callback.Commands, env, timeout)
+		} else {
+			failed, err = d.runCallbackCommands(client, target, exportEnvironments(env, callback.Commands), timeout)
+		}
+
+		if err == nil {
+			d.notify(getCallbackEvent(phase), region, d.AsgNames[region], fmt.Sprintf(":+1: Callback %s is succeeded : %s", callback.Name, region))
+			return nil
+		}
+
+		d.Logger.Warnf("[%s] callback %s failed(%d/%d) : %s", region, callback.Name, attempt, attempts, err.Error())
+
+		// Retry instances which failed only
+		if len(failed) > 0 {
+			target = failed
+		}
+	}
+
+	d.notify(getCallbackEvent(phase), region, d.AsgNames[region], fmt.Sprintf(":warning: Callback %s failed in %s : %s", callback.Name, region, err.Error()))
+	if builder.GetCallbackFailurePolicy(callback) == builder.CALLBACK_FAILURE_CONTINUE {
+		d.Logger.Warnf("deployment continues though callback %s failed", callback.Name)
+		return nil
+	}
+
+	return fmt.Errorf("callback %s failed in %s, so %s is aborted : %s", callback.Name, region, phase, err.Error())
+}
+
+// getCallbackEvent returns the type of notification event for results of callbacks in the phase
+func getCallbackEvent(phase string) string {
+	switch phase {
+	case builder.CALLBACK_PHASE_PRE_DEPLOY:
+		return builder.NOTIFY_EVENT_STARTED
+	case builder.CALLBACK_PHASE_POST_HEALTHY:
+		return builder.NOTIFY_EVENT_HEALTHY
+	case builder.CALLBACK_PHASE_ON_FAILURE:
+		return builder.NOTIFY_EVENT_FAILED
+	}
+
+	return builder.NOTIFY_EVENT_CLEANUP
+}
+
+// getCallbackTargets returns instances on which callback runs
+func (d Deployer) getCallbackTargets(client aws.AWSClient, region, target string) []string {
+	if target == builder.CALLBACK_TARGET_PREVIOUS {
+		return d.PrevInstances[region]
+	}
+
+	asgName, ok := d.AsgNames[region]
+	if !ok {
+		return nil
+	}
+
+	group := client.EC2Service.GetMatchingAutoscalingGroup(asgName)
+	if group == nil {
+		return nil
+	}
+
+	ret := []string{}
+	for _, instance := range group.Instances {
+		if *instance.LifecycleState == "InService" {
+			ret = append(ret, *instance.InstanceId)
+		}
+	}
+
+	return ret
+}
+
+// getCallbackEnvironments returns metadata of deployment as environment variables
+func (d Deployer) getCallbackEnvironments(region, phase string) []string {
+	env := []string{
+		fmt.Sprintf("GOPLOYER_APP=%s", d.AwsConfig.Name),
+		fmt.Sprintf("GOPLOYER_STACK=%s", d.Stack.Stack),
+		fmt.Sprintf("GOPLOYER_ENV=%s", d.Stack.Env),
+		fmt.Sprintf("GOPLOYER_REGION=%s", region),
+		fmt.Sprintf("GOPLOYER_PHASE=%s", phase),
+		fmt.Sprintf("GOPLOYER_NEW_ASG=%s", d.AsgNames[region]),
+		fmt.Sprintf("GOPLOYER_PREVIOUS_ASGS=%s", strings.Join(d.PrevAsgs[region], ",")),
+	}
+
+	if phase == builder.CALLBACK_PHASE_ON_FAILURE {
+		env = append(env, fmt.Sprintf("GOPLOYER_ERROR=%s", tool.GetExitReason()))
+	}
+
+	return env
+}
+
+// exportEnvironments prepends export of environment variables to commands run through SSM
+func exportEnvironments(env []string, commands []string) []string {
+	ret := []string{}
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+		ret = append(ret, fmt.Sprintf("export %s='%s'", kv[0], strings.ReplaceAll(kv[1], "'", `'\''`)))
+	}
+
+	return append(ret, commands...)
+}
+
+// runLocalCommands runs commands in the host running goployer
+// Commands run as one shell script like AWS-RunShellScript.
+func (d Deployer) runLocalCommands(commands []string, env []string, timeout int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", strings.Join(commands, "\n"))
+	cmd.Env = append(os.Environ(), env...)
+
+	output, err := cmd.CombinedOutput()
+	if len(output) > 0 {
+		d.Logger.Infof("[local] output : %s", strings.TrimSpace(string(output)))
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("commands are not finished in %d seconds", timeout)
+	}
+
+	return err
+}
+
+// runCallbackCommands sends commands to instances and waits for the results until timeout
+// It returns instances in which commands did not succeed.
+func (d Deployer) runCallbackCommands(client aws.AWSClient, target []string, commands []string, timeout int64) ([]string, error) {
+	commandId, err := client.SSMService.SendCommandWithComment(
+		aws.MakeStringArrayToAwsStrings(target),
+		aws.MakeStringArrayToAwsStrings(commands),
+		"goployer lifecycle callbacks",
+		timeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.Logger.Infof("lifecycle callbacks are sent : %s", commandId)
+
+	commandIds := map[string]string{}
+	for _, instanceId := range target {
+		commandIds[instanceId] = commandId
+	}
+
+	invocations := client.SSMService.WaitCommandInvocations(commandIds, timeout)
+
+	failed := []string{}
+	for _, instanceId := range target {
+		invocation, ok := invocations[instanceId]
+		if !ok {
+			d.Logger.Warnf("[%s] no result of lifecycle callbacks", instanceId)
+			failed = append(failed, instanceId)
+			continue
+		}
+
+		aws.LogCommandInvocation("lifecycle callbacks", invocation)
+		if !aws.IsCommandSucceeded(invocation) {
+			failed = append(failed, instanceId)
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed, fmt.Errorf("%d/%d instances failed : %s", len(failed), len(target), strings.Join(failed, ", "))
+	}
+
+	return nil, nil
+}
@@ -2,14 +2,20 @@ package deployer
 
 import (
 	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"time"
 )
 
 type DeployManager interface {
 	GetStackName() string
 	Deploy(config builder.Config)
-	HealthChecking(config builder.Config) map[string]bool
+	HealthChecking(config builder.Config) (map[string]bool, error)
+	HealthGating(config builder.Config) (map[string]bool, error)
 	FinishAdditionalWork(config builder.Config) error
 	CleanPreviousVersion(config builder.Config) error
 	TriggerLifecycleCallbacks(config builder.Config) error
+	TriggerCallbacks(config builder.Config, phase string) error
 	TerminateChecking(config builder.Config) map[string]bool
+	NextPollInterval(phase string) time.Duration
+	CheckPhaseTimeout(phase string) error
+	RollbackOnFailure(config builder.Config) error
 }
@@ -1,29 +1,53 @@
 package deployer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/DevopsArtFactory/goployer/pkg/aws"
 	"github.com/DevopsArtFactory/goployer/pkg/builder"
 	"github.com/DevopsArtFactory/goployer/pkg/collector"
+	"github.com/DevopsArtFactory/goployer/pkg/healthchecker"
+	"github.com/DevopsArtFactory/goployer/pkg/notifier"
 	"github.com/DevopsArtFactory/goployer/pkg/tool"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	Logger "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+var (
+	ROLLBACK_DRAIN_INTERVAL = 10 * time.Second
+	ROLLBACK_DRAIN_MARGIN   = 30 * time.Second
 )
 
 // Deployer per stack
 type Deployer struct {
-	Mode          string
-	AsgNames      map[string]string
-	PrevAsgs      map[string][]string
-	PrevInstances map[string][]string
-	Logger        *Logger.Logger
-	Stack         builder.Stack
-	AwsConfig     builder.AWSConfig
-	AWSClients    []aws.AWSClient
-	LocalProvider builder.UserdataProvider
-	Slack         tool.Slack
-	Collector     collector.Collector
+	Mode           string
+	AsgNames       map[string]string
+	PrevAsgs       map[string][]string
+	PrevInstances  map[string][]string
+	Logger         *Logger.Logger
+	Stack          builder.Stack
+	AwsConfig      builder.AWSConfig
+	AWSClients     []aws.AWSClient
+	LocalProvider  builder.UserdataProvider
+	Notifier       notifier.Notifier
+	Collector      collector.Collector
+	HealthCheckers map[string]healthchecker.HealthChecker
+	GateStartTimes map[string]time.Time
+	// Count of consecutive successful health checks per region
+	HealthySuccesses map[string]int64
+	PhaseStartTimes  map[string]time.Time
+	Polls            map[string]int
+	SeenActivities   map[string]bool
+	// AMI of new autoscaling group per region
+	Amis map[string]string
+	// Start time of draining per previous autoscaling group
+	DrainStartTimes map[string]time.Time
+	// Readiness checkers and completed launch lifecycle actions per hook
+	ReadinessCheckers map[string]healthchecker.ReadinessChecker
+	CompletedHooks    map[string]bool
 }
 
 // getCurrentVersion returns current version for current deployment step
@@ -35,17 +59,24 @@ func getCurrentVersion(prevVersions []int) int {
 }
 
 // Polling for healthcheck
-func (d Deployer) polling(region builder.RegionConfig, asg *autoscaling.Group, client aws.AWSClient) bool {
+// Errors of health checkers are returned so that failure policy of the stack is applied.
+func (d Deployer) polling(region builder.RegionConfig, asg *autoscaling.Group, client aws.AWSClient) (bool, error) {
 	if *asg.AutoScalingGroupName == "" {
 		tool.ErrorLogging(fmt.Sprintf("No autoscaling found for %s", d.AsgNames[region.Region]))
 	}
 
-	healthcheckTargetGroup := region.HealthcheckTargetGroup
-	healthcheckTargetGroupArn := (client.ELBService.GetTargetGroupARNs([]string{healthcheckTargetGroup}))[0]
+	checker, err := d.getHealthChecker(region, client)
+	if err != nil {
+		return false, err
+	}
 
-	threshold := d.Stack.Capacity.Desired
-	targetHosts := client.ELBService.GetHostInTarget(asg, healthcheckTargetGroupArn)
+	threshold := builder.GetHealthyThreshold(d.Stack.Healthcheck, builder.ApplyRegionOverrides(d.Stack, region).Capacity.Desired)
+	targetHosts, err := checker.Check(asg)
+	if err != nil {
+		return false, fmt.Errorf("health check of %s failed with %s : %s", d.AsgNames[region.Region], checker.Name(), err.Error())
+	}
 
+	Logger.Debugf("Checking health of instances with %s", checker.Name())
 	healthHostCount := int64(0)
 
 	for _, host := range targetHosts {
@@ -56,16 +87,467 @@ func (d Deployer) polling(region builder.RegionConfig, asg *autoscaling.Group, c
 	}
 
 	if healthHostCount >= threshold {
+		d.HealthySuccesses[region.Region]++
+		consecutiveSuccesses := d.Stack.Healthcheck.ConsecutiveSuccesses
+		if d.HealthySuccesses[region.Region] < consecutiveSuccesses {
+			Logger.Info(fmt.Sprintf("Healthy count meets the requirement(%s) : %d/%d, %d/%d consecutive successes", d.AsgNames[region.Region], healthHostCount, threshold, d.HealthySuccesses[region.Region], consecutiveSuccesses))
+			return false, nil
+		}
+
 		// Success
 		Logger.Info(fmt.Sprintf("Healthy Count for %s : %d/%d", d.AsgNames[region.Region], healthHostCount, threshold))
-		d.Slack.SendSimpleMessage(fmt.Sprintf("All instances are healthy in %s  :  %d/%d", d.AsgNames[region.Region], healthHostCount, threshold), d.Stack.Env)
-		return true
+		d.notify(builder.NOTIFY_EVENT_HEALTHY, region.Region, d.AsgNames[region.Region], fmt.Sprintf("All instances are healthy in %s  :  %d/%d", d.AsgNames[region.Region], healthHostCount, threshold))
+		return true, nil
 	}
 
+	d.HealthySuccesses[region.Region] = 0
 	Logger.Info(fmt.Sprintf("Healthy count does not meet the requirement(%s) : %d/%d", d.AsgNames[region.Region], healthHostCount, threshold))
-	d.Slack.SendSimpleMessage(fmt.Sprintf("Waiting for healthy instances %s  :  %d/%d", d.AsgNames[region.Region], healthHostCount, threshold), d.Stack.Env)
+	d.notify(builder.NOTIFY_EVENT_WAITING, region.Region, d.AsgNames[region.Region], fmt.Sprintf("Waiting for healthy instances %s  :  %d/%d", d.AsgNames[region.Region], healthHostCount, threshold))
+
+	return false, nil
+}
+
+// NextPollInterval returns interval before the next poll of the phase
+func (d Deployer) NextPollInterval(phase string) time.Duration {
+	interval := builder.GetPollInterval(d.Stack.Healthcheck, d.Polls[phase])
+	d.Polls[phase]++
+
+	// Health gates should be evaluated as soon as the window ends
+	if phase == builder.PHASE_HEALTH_GATE {
+		if remaining := d.remainingGateWindow(); remaining < interval {
+			return remaining
+		}
+	}
+
+	return interval
+}
+
+// remainingGateWindow returns the time until the earliest window of health gates which is not ended yet
+func (d Deployer) remainingGateWindow() time.Duration {
+	window := time.Duration(builder.GetHealthGateWindow(d.Stack.HealthGates)) * time.Second
+	remaining := window
+	for _, start := range d.GateStartTimes {
+		if r := time.Until(start.Add(window)); r > 0 && r < remaining {
+			remaining = r
+		}
+	}
+
+	// Datapoints at the end of window could be delayed a little
+	return remaining + time.Second
+}
+
+// CheckPhaseTimeout checks if the phase exceeds timeout of the stack
+// The phase starts when this is called for the first time.
+func (d Deployer) CheckPhaseTimeout(phase string) error {
+	start, ok := d.PhaseStartTimes[phase]
+	if !ok {
+		d.PhaseStartTimes[phase] = time.Now()
+		return nil
+	}
+
+	timeout := builder.GetPhaseTimeout(d.Stack.Healthcheck, phase)
+	if timeout > 0 && time.Since(start) > time.Duration(timeout)*time.Minute {
+		return fmt.Errorf("%s phase of stack %s has exceeded timeout : %d minutes", phase, d.Stack.Stack, timeout)
+	}
+
+	return nil
+}
+
+// phaseContext returns context which is done when the phase exceeds timeout of the stack
+// Context is not done by timeout if the phase has no timeout.
+func (d Deployer) phaseContext(phase string) (context.Context, context.CancelFunc) {
+	timeout := builder.GetPhaseTimeout(d.Stack.Healthcheck, phase)
+	start, ok := d.PhaseStartTimes[phase]
+	if timeout <= 0 || !ok {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithDeadline(context.Background(), start.Add(time.Duration(timeout)*time.Minute))
+}
+
+// getHealthChecker returns health checker of the region
+// Health checker is created once per region and reused while polling.
+func (d Deployer) getHealthChecker(region builder.RegionConfig, client aws.AWSClient) (healthchecker.HealthChecker, error) {
+	if checker, ok := d.HealthCheckers[region.Region]; ok {
+		return checker, nil
+	}
+
+	checker, err := healthchecker.NewHealthChecker(d.Stack.Healthcheck, region, client)
+	if err != nil {
+		return nil, err
+	}
+	d.HealthCheckers[region.Region] = checker
+
+	return checker, nil
+}
+
+// getReadinessChecker returns readiness checker of the launch lifecycle hook in the region
+func (d Deployer) getReadinessChecker(region builder.RegionConfig, hook builder.LifecycleHookSpecification, client aws.AWSClient) (healthchecker.ReadinessChecker, error) {
+	key := fmt.Sprintf("%s/%s", region.Region, hook.LifecycleHookName)
+	if checker, ok := d.ReadinessCheckers[key]; ok {
+		return checker, nil
+	}
+
+	checker, err := healthchecker.NewReadinessChecker(hook.Readiness, client)
+	if err != nil {
+		return nil, err
+	}
+	d.ReadinessCheckers[key] = checker
+
+	return checker, nil
+}
+
+// completeLaunchHooks completes launch lifecycle actions of instances which pass readiness check
+// Heartbeat is recorded for instances not ready yet, so that they are not timed out while bootstrapping.
+func (d Deployer) completeLaunchHooks(region builder.RegionConfig, group *autoscaling.Group, client aws.AWSClient) error {
+	waiting := []string{}
+	for _, instance := range group.Instances {
+		if *instance.LifecycleState == autoscaling.LifecycleStatePendingWait {
+			waiting = append(waiting, *instance.InstanceId)
+		}
+	}
+
+	if len(waiting) == 0 {
+		return nil
+	}
+
+	for _, hook := range d.Stack.LifecycleHooks.LaunchTransition {
+		if !builder.HasReadiness(hook) {
+			continue
+		}
+
+		targets := []string{}
+		for _, instanceId := range waiting {
+			if !d.CompletedHooks[fmt.Sprintf("%s/%s", hook.LifecycleHookName, instanceId)] {
+				targets = append(targets, instanceId)
+			}
+		}
+
+		if len(targets) == 0 {
+			continue
+		}
+
+		checker, err := d.getReadinessChecker(region, hook, client)
+		if err != nil {
+			return err
+		}
+
+		ready, err := checker.CheckInstances(targets)
+		if err != nil {
+			return err
+		}
+
+		notReady := 0
+		for _, instanceId := range targets {
+			if ready[instanceId] {
+				if err := client.EC2Service.CompleteLifecycleAction(*group.AutoScalingGroupName, hook.LifecycleHookName, instanceId, "CONTINUE"); err != nil {
+					d.Logger.Warnf("failed to complete lifecycle action of %s : %s", instanceId, err.Error())
+					continue
+				}
+				d.CompletedHooks[fmt.Sprintf("%s/%s", hook.LifecycleHookName, instanceId)] = true
+				continue
+			}
+
+			notReady++
+			if err := client.EC2Service.RecordLifecycleActionHeartbeat(*group.AutoScalingGroupName, hook.LifecycleHookName, instanceId); err != nil {
+				d.Logger.Warnf("failed to record heartbeat of %s : %s", instanceId, err.Error())
+			}
+		}
+
+		d.Logger.Infof("[%s] readiness of %s with %s : %d/%d instances are ready", region.Region, hook.LifecycleHookName, checker.Name(), len(targets)-notReady, len(targets))
+	}
+
+	return nil
+}
+
+// checkHealthGates checks metrics of the new autoscaling group with health gates
+// It returns true if no gate is breached during the window, and error if any gate is breached or has no datapoint.
+func (d Deployer) checkHealthGates(region builder.RegionConfig, client aws.AWSClient) (bool, error) {
+	now := time.Now()
+	start, ok := d.GateStartTimes[region.Region]
+	if !ok {
+		start = now
+		d.GateStartTimes[region.Region] = start
+		d.notify(builder.NOTIFY_EVENT_WAITING, region.Region, d.AsgNames[region.Region], fmt.Sprintf("Start watching health gates of %s for %d seconds", d.AsgNames[region.Region], builder.GetHealthGateWindow(d.Stack.HealthGates)))
+	}
+
+	windowEnded := now.Sub(start) >= time.Duration(builder.GetHealthGateWindow(d.Stack.HealthGates))*time.Second
+	for _, gate := range d.Stack.HealthGates.Gates {
+		dimensions, err := d.getHealthGateDimensions(gate, region, client)
+		if err != nil {
+			return false, err
+		}
+
+		datapoints, err := client.CloudWatchService.GetMetricDatapoints(gate, dimensions, start, now)
+		if err != nil {
+			return false, err
+		}
 
-	return false
+		breached := []float64{}
+		for _, value := range datapoints {
+			if gate.IsBreached(value) {
+				breached = append(breached, value)
+			}
+		}
+
+		d.Logger.Infof("[%s] health gate %s : %d/%d datapoints are breached", region.Region, gate.Name, len(breached), len(datapoints))
+
+		datapointsToBreach := gate.DatapointsToBreach
+		if datapointsToBreach == 0 {
+			datapointsToBreach = 1
+		}
+
+		if int64(len(breached)) >= datapointsToBreach {
+			return false, fmt.Errorf("health gate %s is breached in %s : %s %s %v, datapoints %v", gate.Name, d.AsgNames[region.Region], gate.Statistic, gate.Comparison, gate.Threshold, breached)
+		}
+
+		// Datapoints are read from the start of the window, so no datapoint at the end means the metric is not found
+		if windowEnded && len(datapoints) == 0 && gate.IsMissingDataBreached() {
+			return false, fmt.Errorf("health gate %s has no datapoint during the window in %s, please check namespace, metric and dimensions : %s/%s %v", gate.Name, d.AsgNames[region.Region], gate.Namespace, gate.Metric, dimensions)
+		}
+	}
+
+	if !windowEnded {
+		return false, nil
+	}
+
+	d.notify(builder.NOTIFY_EVENT_HEALTHY, region.Region, d.AsgNames[region.Region], fmt.Sprintf(":+1: All health gates are passed : %s", d.AsgNames[region.Region]))
+
+	return true, nil
+}
+
+// getHealthGateDimensions returns dimensions of the health gate metric
+func (d Deployer) getHealthGateDimensions(gate builder.HealthGate, region builder.RegionConfig, client aws.AWSClient) ([]string, error) {
+	dimensions, err := d.getScopeDimensions(gate.Scope, region, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(dimensions, gate.Dimensions...), nil
+}
+
+// getScopeDimensions returns dimensions of the metric scope in the region
+// Empty scope means the new autoscaling group.
+func (d Deployer) getScopeDimensions(scope string, region builder.RegionConfig, client aws.AWSClient) ([]string, error) {
+	dimensions := []string{}
+	switch scope {
+	case builder.METRIC_SCOPE_NONE:
+	case builder.METRIC_SCOPE_TARGET_GROUP:
+		arn, err := client.ELBService.FindTargetGroupARN(builder.GetPrimaryTargetGroup(region))
+		if err != nil {
+			return nil, err
+		}
+
+		// CloudWatch uses the last part of ARN like targetgroup/name/id
+		dimensions = append(dimensions, fmt.Sprintf("TargetGroup=%s", arn[strings.LastIndex(arn, ":")+1:]))
+	default:
+		dimensions = append(dimensions, fmt.Sprintf("AutoScalingGroupName=%s", d.AsgNames[region.Region]))
+	}
+
+	return dimensions, nil
+}
+
+// getAlarms returns alarms with dimensions of their scope
+func (d Deployer) getAlarms(alarms []builder.AlarmConfigs, region builder.RegionConfig, client aws.AWSClient) ([]builder.AlarmConfigs, error) {
+	ret := []builder.AlarmConfigs{}
+	for _, alarm := range alarms {
+		dimensions, err := d.getScopeDimensions(alarm.Scope, region, client)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(alarm.Metrics) == 0 {
+			alarm.Dimensions = append(append([]string{}, dimensions...), alarm.Dimensions...)
+			ret = append(ret, alarm)
+			continue
+		}
+
+		metrics := []builder.AlarmMetric{}
+		for _, m := range alarm.Metrics {
+			if len(m.Expression) == 0 {
+				m.Dimensions = append(append([]string{}, dimensions...), m.Dimensions...)
+			}
+			metrics = append(metrics, m)
+		}
+		alarm.Metrics = metrics
+		ret = append(ret, alarm)
+	}
+
+	return ret, nil
+}
+
+// suspendProcesses suspends processes of the autoscaling group during deployment
+// Processes are resumed by exit hook if deployment fails before they are resumed.
+func (d Deployer) suspendProcesses(client aws.AWSClient, asg string) error {
+	if len(d.Stack.SuspendProcesses) == 0 {
+		return nil
+	}
+
+	if err := client.EC2Service.SuspendProcesses(asg, d.Stack.SuspendProcesses); err != nil {
+		return err
+	}
+
+	tool.RegisterExitHook(getResumeHookName(asg), func() {
+		if err := client.EC2Service.ResumeProcesses(asg, d.Stack.SuspendProcesses); err != nil {
+			d.Logger.Errorf("failed to resume processes of %s : %s", asg, err.Error())
+		}
+	})
+
+	return nil
+}
+
+// resumeProcesses resumes processes suspended during deployment
+func (d Deployer) resumeProcesses(client aws.AWSClient, asg string) error {
+	if len(d.Stack.SuspendProcesses) == 0 {
+		return nil
+	}
+
+	if err := client.EC2Service.ResumeProcesses(asg, d.Stack.SuspendProcesses); err != nil {
+		return err
+	}
+
+	tool.UnregisterExitHook(getResumeHookName(asg))
+
+	return nil
+}
+
+// getResumeHookName returns name of exit hook for resuming processes
+func getResumeHookName(asg string) string {
+	return fmt.Sprintf("resume-processes:%s", asg)
+}
+
+// checkLaunchFailures checks scaling activities of the new autoscaling group
+// If instances fail to launch or are replaced repeatedly by health checks, the deployment cannot succeed.
+// The returned failure describes why the deployment failed, and it is empty if not failed.
+// The error is of reading activities, not the verdict of the check.
+func (d Deployer) checkLaunchFailures(region builder.RegionConfig, client aws.AWSClient) (string, error) {
+	threshold := builder.GetLaunchFailureThreshold(d.Stack.Healthcheck)
+	if threshold == 0 {
+		return "", nil
+	}
+
+	asgName := d.AsgNames[region.Region]
+	activities, err := client.EC2Service.GetScalingActivities(asgName)
+	if err != nil {
+		return "", err
+	}
+
+	failures := int64(0)
+	lastMessage := ""
+	for _, activity := range activities {
+		if !isLaunchFailure(activity) {
+			continue
+		}
+
+		failures++
+		message := *activity.Cause
+		if activity.StatusMessage != nil && len(*activity.StatusMessage) > 0 {
+			message = *activity.StatusMessage
+		}
+
+		// Activities are sorted by start time in descending order
+		if len(lastMessage) == 0 {
+			lastMessage = message
+		}
+
+		if d.SeenActivities[*activity.ActivityId] {
+			continue
+		}
+		d.SeenActivities[*activity.ActivityId] = true
+
+		d.Logger.Warnf("[%s] %s(%s) : %s", asgName, *activity.Description, *activity.StatusCode, message)
+		d.notify(builder.NOTIFY_EVENT_WAITING, region.Region, asgName, fmt.Sprintf(":warning: %s in %s : %s", *activity.Description, asgName, message))
+	}
+
+	if failures >= threshold {
+		return fmt.Sprintf("instances failed to launch or were replaced by health checks %d times in %s : %s", failures, asgName, lastMessage), nil
+	}
+
+	return "", nil
+}
+
+// isLaunchFailure checks if the activity failed, or terminated an instance because it was unhealthy
+// Other terminations like scaling in are not failures of the deployment.
+func isLaunchFailure(activity *autoscaling.Activity) bool {
+	if *activity.StatusCode == autoscaling.ScalingActivityStatusCodeFailed || *activity.StatusCode == autoscaling.ScalingActivityStatusCodeCancelled {
+		return true
+	}
+
+	return strings.HasPrefix(*activity.Description, "Terminating EC2 instance") &&
+		activity.Cause != nil && strings.Contains(*activity.Cause, "taken out of service in response to")
+}
+
+// notify sends the event of deployment to notifiers of the stack
+// Deployment goes on even if notification fails.
+func (d Deployer) notify(eventType, region, asg, message string) {
+	if d.Notifier == nil {
+		return
+	}
+
+	event := notifier.NewEvent(eventType, d.AwsConfig.Name, d.Stack.Stack, d.Stack.Env, region, asg, message)
+	if err := d.Notifier.Notify(event); err != nil {
+		d.Logger.Warnln(err.Error())
+	}
+}
+
+// handleFailure handles the failure of deployment with on_failure setting
+func (d Deployer) handleFailure(config builder.Config, onFailure string, err error) error {
+	d.notify(builder.NOTIFY_EVENT_FAILED, "", "", fmt.Sprintf(":x: %s", err.Error()))
+	if onFailure != builder.ON_FAILURE_ROLLBACK {
+		return err
+	}
+
+	if rerr := d.rollback(config); rerr != nil {
+		return fmt.Errorf("%s, and rollback failed : %s", err.Error(), rerr.Error())
+	}
+
+	return fmt.Errorf("%s, so deployment is rolled back", err.Error())
+}
+
+// rollback deletes the new autoscaling groups
+// Previous autoscaling groups are not touched, so they keep serving.
+func (d Deployer) rollback(config builder.Config) error {
+	for _, region := range d.Stack.Regions {
+		if config.Region != "" && config.Region != region.Region {
+			continue
+		}
+
+		target, ok := d.AsgNames[region.Region]
+		if !ok {
+			continue
+		}
+
+		client, err := selectClientFromList(d.AWSClients, region.Region)
+		if err != nil {
+			return err
+		}
+
+		d.Logger.Warnf("Rolling back the deployment : %s", target)
+		d.notify(builder.NOTIFY_EVENT_FAILED, region.Region, target, fmt.Sprintf(":rewind: Rolling back the deployment : %s", target))
+
+		// Detach from load balancers first, so in-flight requests to new instances are not dropped
+		if err := d.drainForRollback(client, target); err != nil {
+			return err
+		}
+
+		if err := client.EC2Service.ForceDeleteAutoscalingGroup(target); err != nil {
+			return err
+		}
+		tool.UnregisterExitHook(getResumeHookName(target))
+		delete(d.AsgNames, region.Region)
+
+		if err := client.EC2Service.DeleteLaunchTemplates(target); err != nil {
+			return err
+		}
+
+		if d.Collector.MetricConfig.Enabled {
+			if err := d.Collector.UpdateStatus(target, "rollback", nil); err != nil {
+				d.Logger.Errorf("Update status Error, %s : %s", err.Error(), target)
+			}
+		}
+	}
+
+	return nil
 }
 
 // CheckTerminating checks if all of instances are terminated well
@@ -76,14 +558,46 @@ func (d Deployer) CheckTerminating(client aws.AWSClient, target string) bool {
 		return true
 	}
 
+	// Resize to 0 after draining is finished
+	if _, ok := d.DrainStartTimes[target]; ok {
+		drained, err := d.checkDraining(client, asgInfo)
+		if err != nil {
+			d.Logger.Errorln(err.Error())
+			return false
+		}
+
+		if !drained {
+			return false
+		}
+
+		if err := d.ResizingAutoScalingGroupToZero(client, d.Stack.Stack, target); err != nil {
+			return false
+		}
+		delete(d.DrainStartTimes, target)
+
+		return false
+	}
+
 	d.Logger.Info(fmt.Sprintf("Waiting for instance termination in asg %s", target))
 	if len(asgInfo.Instances) > 0 {
 		d.Logger.Info(fmt.Sprintf("%d instance found : %s", len(asgInfo.Instances), target))
-		d.Slack.SendSimpleMessage(fmt.Sprintf("Still %d instance found : %s", len(asgInfo.Instances), target), d.Stack.Env)
+		d.notify(builder.NOTIFY_EVENT_CLEANUP, client.Region, target, fmt.Sprintf("Still %d instance found : %s", len(asgInfo.Instances), target))
 
 		return false
 	}
-	d.Slack.SendSimpleMessage(fmt.Sprintf(":+1: All instances are deleted : %s", target), d.Stack.Env)
+	d.notify(builder.NOTIFY_EVENT_CLEANUP, client.Region, target, fmt.Sprintf(":+1: All instances are deleted : %s", target))
+
+	// Autoscaling group cannot be deleted while warm pool exists
+	if asgInfo.WarmPoolConfiguration != nil {
+		if status := asgInfo.WarmPoolConfiguration.Status; status == nil || *status != autoscaling.WarmPoolStatusPendingDelete {
+			if err := client.EC2Service.DeleteWarmPool(target); err != nil {
+				d.Logger.Errorln(err.Error())
+			}
+		}
+
+		d.Logger.Info(fmt.Sprintf("Waiting for warm pool deletion : %s", target))
+		return false
+	}
 
 	d.Logger.Debug(fmt.Sprintf("Start deleting autoscaling group : %s", target))
 	ok := client.EC2Service.DeleteAutoscalingSet(target)
@@ -91,6 +605,12 @@ func (d Deployer) CheckTerminating(client aws.AWSClient, target string) bool {
 		return false
 	}
 	d.Logger.Debug(fmt.Sprintf("Autoscaling group is deleted : %s", target))
+	tool.UnregisterExitHook(getResumeHookName(target))
+
+	if err := client.CloudWatchService.DeleteAlarmsOfAutoscalingGroup(target); err != nil {
+		d.Logger.Errorln(err.Error())
+		return false
+	}
 
 	additionalAttributes, err := d.Collector.GetAdditionalMetric(target)
 	if err != nil {
@@ -114,7 +634,7 @@ func (d Deployer) CheckTerminating(client aws.AWSClient, target string) bool {
 // ResizingAutoScalingGroupToZero set autoscaling group instance count to 0
 func (d Deployer) ResizingAutoScalingGroupToZero(client aws.AWSClient, stack, asg string) error {
 	d.Logger.Info(fmt.Sprintf("Modifying the size of autoscaling group to 0 : %s(%s)", asg, stack))
-	d.Slack.SendSimpleMessage(fmt.Sprintf("Modifying the size of autoscaling group to 0 : %s/%s", asg, stack), d.Stack.Env)
+	d.notify(builder.NOTIFY_EVENT_CLEANUP, client.Region, asg, fmt.Sprintf("Modifying the size of autoscaling group to 0 : %s/%s", asg, stack))
 	err := client.EC2Service.UpdateAutoScalingGroup(asg, 0, 0, 0)
 	if err != nil {
 		d.Logger.Errorln(err.Error())
@@ -124,27 +644,186 @@ func (d Deployer) ResizingAutoScalingGroupToZero(client aws.AWSClient, stack, as
 	return nil
 }
 
-// RunLifecycleCallbacks runs commands before terminating.
-func (d Deployer) RunLifecycleCallbacks(client aws.AWSClient, target []string) bool {
+// DrainAutoScalingGroup detaches load balancers and target groups from the previous autoscaling group
+// The autoscaling group is resized to 0 after draining is finished in CheckTerminating.
+// If nothing is attached, then it is resized to 0 right away.
+func (d Deployer) DrainAutoScalingGroup(client aws.AWSClient, stack, asg string) error {
+	group := client.EC2Service.GetMatchingAutoscalingGroup(asg)
+	if group == nil || (len(group.LoadBalancerNames) == 0 && len(group.TargetGroupARNs) == 0) {
+		return d.ResizingAutoScalingGroupToZero(client, stack, asg)
+	}
 
-	if len(target) == 0 {
-		d.Logger.Debugf("no target instance exists\n")
-		return false
+	delay, err := getDrainingDelay(client, group)
+	if err != nil {
+		return err
+	}
+
+	if err := client.EC2Service.DetachLoadBalancers(asg, group.LoadBalancerNames, group.TargetGroupARNs); err != nil {
+		d.Logger.Errorln(err.Error())
+		return err
+	}
+
+	d.DrainStartTimes[asg] = time.Now()
+	d.Logger.Info(fmt.Sprintf("Draining autoscaling group : %s(%s), up to %d seconds", asg, stack, delay))
+	d.notify(builder.NOTIFY_EVENT_CLEANUP, client.Region, asg, fmt.Sprintf(":hourglass_flowing_sand: Draining autoscaling group : %s/%s, up to %d seconds", asg, stack, delay))
+
+	return nil
+}
+
+// getDrainingDelay returns the longest connection draining timeout or deregistration delay of load balancers
+func getDrainingDelay(client aws.AWSClient, group *autoscaling.Group) (int64, error) {
+	delay := int64(0)
+	for _, lb := range group.LoadBalancerNames {
+		timeout, err := client.ClassicELBService.GetConnectionDrainingTimeout(*lb)
+		if err != nil {
+			return 0, err
+		}
+		if timeout > delay {
+			delay = timeout
+		}
+	}
+
+	for _, arn := range group.TargetGroupARNs {
+		timeout, err := client.ELBService.GetDeregistrationDelay(*arn)
+		if err != nil {
+			return 0, err
+		}
+		if timeout > delay {
+			delay = timeout
+		}
+	}
+
+	return delay, nil
+}
+
+// drainForRollback detaches load balancers from the new autoscaling group, and waits until draining is finished
+// Rollback is synchronous, so it waits here up to the draining delay of load balancers.
+func (d Deployer) drainForRollback(client aws.AWSClient, asg string) error {
+	group := client.EC2Service.GetMatchingAutoscalingGroup(asg)
+	if group == nil || (len(group.LoadBalancerNames) == 0 && len(group.TargetGroupARNs) == 0) {
+		return nil
+	}
+
+	delay, err := getDrainingDelay(client, group)
+	if err != nil {
+		return err
+	}
+
+	if err := client.EC2Service.DetachLoadBalancers(asg, group.LoadBalancerNames, group.TargetGroupARNs); err != nil {
+		return err
+	}
+
+	d.DrainStartTimes[asg] = time.Now()
+	defer delete(d.DrainStartTimes, asg)
+
+	deadline := time.Now().Add(time.Duration(delay)*time.Second + ROLLBACK_DRAIN_MARGIN)
+	for {
+		drained, err := d.checkDraining(client, group)
+		if err != nil {
+			return err
+		}
+
+		if drained {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			d.Logger.Warnf("Draining is not finished in %d seconds, so %s is deleted anyway", delay, asg)
+			return nil
+		}
+
+		time.Sleep(ROLLBACK_DRAIN_INTERVAL)
+	}
+}
+
+// checkDraining checks if load balancers and target groups are detached from the autoscaling group
+// In-flight connections are reported with the number of draining targets.
+func (d Deployer) checkDraining(client aws.AWSClient, group *autoscaling.Group) (bool, error) {
+	asg := *group.AutoScalingGroupName
+	detaching, err := client.EC2Service.GetDetachingLoadBalancers(asg)
+	if err != nil {
+		return false, err
+	}
+
+	if len(detaching) == 0 {
+		d.Logger.Info(fmt.Sprintf("Draining is finished : %s", asg))
+		d.notify(builder.NOTIFY_EVENT_CLEANUP, client.Region, asg, fmt.Sprintf(":+1: Draining is finished : %s", asg))
+		return true, nil
+	}
+
+	instanceIds := []string{}
+	for _, instance := range group.Instances {
+		instanceIds = append(instanceIds, *instance.InstanceId)
+	}
+
+	draining := 0
+	for _, target := range detaching {
+		if !strings.HasPrefix(target, "arn:") {
+			continue
+		}
+
+		count, err := client.ELBService.GetDrainingTargetCount(target, instanceIds)
+		if err != nil {
+			return false, err
+		}
+		draining += count
 	}
 
-	commands := []string{}
+	elapsed := time.Since(d.DrainStartTimes[asg]).Round(time.Second)
+	d.Logger.Info(fmt.Sprintf("Waiting for draining : %s, %d targets are draining, %s elapsed", asg, draining, elapsed))
+	d.notify(builder.NOTIFY_EVENT_CLEANUP, client.Region, asg, fmt.Sprintf("Waiting for draining : %s, %d targets are draining, %s elapsed", asg, draining, elapsed))
 
-	for _, command := range d.Stack.LifecycleCallbacks.PreTerminatePastClusters {
-		commands = append(commands, command)
+	return false, nil
+}
+
+// getAmi returns AMI id to use in the region
+// If AMI should be copied from other region, then AMI of the source region is copied to the region.
+func (d Deployer) getAmi(config builder.Config, region builder.RegionConfig, client aws.AWSClient) (string, error) {
+	if !builder.NeedAmiCopy(d.Stack, region) {
+		amiId, amiSelector, err := builder.GetAmiSelector(config, region)
+		if err != nil {
+			return "", err
+		}
+		return client.ResolveAmi(amiId, amiSelector)
 	}
 
-	d.Logger.Debugf("run lifecycle callbacks before termination : %s", target)
-	client.SSMService.SendCommand(
-		aws.MakeStringArrayToAwsStrings(target),
-		aws.MakeStringArrayToAwsStrings(commands),
-	)
+	sourceRegion := builder.GetAmiCopySourceRegion(d.Stack)
+	sourceClient, err := selectClientFromList(d.AWSClients, sourceRegion)
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range d.Stack.Regions {
+		if r.Region != sourceRegion {
+			continue
+		}
+
+		sourceAmi, err := d.getAmi(config, r, sourceClient)
+		if err != nil {
+			return "", err
+		}
+
+		image, err := sourceClient.EC2Service.GetImage(sourceAmi)
+		if err != nil {
+			return "", err
+		}
+
+		d.Logger.Infof("Copying AMI %s from %s to %s", sourceAmi, sourceRegion, region.Region)
+		d.notify(builder.NOTIFY_EVENT_STARTED, region.Region, "", fmt.Sprintf("Copying AMI %s from %s to %s", sourceAmi, sourceRegion, region.Region))
+
+		// Copy could take long, so it stops at timeout of deploy phase
+		ctx, cancel := d.phaseContext(builder.PHASE_DEPLOY)
+		defer cancel()
+
+		copied, err := client.EC2Service.CopyImage(ctx, image, sourceRegion, region.AmiCopyKmsKeyId)
+		if err != nil && ctx.Err() != nil {
+			return "", fmt.Errorf("%s phase of stack %s has exceeded timeout while copying AMI %s to %s : %s", builder.PHASE_DEPLOY, d.Stack.Stack, sourceAmi, region.Region, err.Error())
+		}
+
+		return copied, err
+	}
 
-	return false
+	return "", fmt.Errorf("no source region for AMI copy exists : %s", sourceRegion)
 }
 
 // selectClientFromList get aws client.
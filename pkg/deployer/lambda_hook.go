@@ -0,0 +1,59 @@
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+)
+
+// LambdaPayload describes the deployment for Lambda hooks
+type LambdaPayload struct {
+	App          string   `json:"app"`
+	Stack        string   `json:"stack"`
+	Env          string   `json:"env"`
+	Region       string   `json:"region"`
+	Phase        string   `json:"phase"`
+	NewAsg       string   `json:"new_asg"`
+	PreviousAsgs []string `json:"previous_asgs"`
+	Ami          string   `json:"ami"`
+	ReleaseNotes string   `json:"release_notes"`
+}
+
+// RunLambdaHooks invokes Lambda functions of the phase in the region
+// It returns error if any function fails, so that the phase fails.
+func (d Deployer) RunLambdaHooks(client aws.AWSClient, config builder.Config, region, phase string) error {
+	hooks := builder.GetLambdaHooks(d.Stack.LambdaHooks, phase)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(LambdaPayload{
+		App:          d.AwsConfig.Name,
+		Stack:        d.Stack.Stack,
+		Env:          d.Stack.Env,
+		Region:       region,
+		Phase:        phase,
+		NewAsg:       d.AsgNames[region],
+		PreviousAsgs: d.PrevAsgs[region],
+		Ami:          d.Amis[region],
+		ReleaseNotes: builder.GetReleaseNotes(config),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range hooks {
+		d.Logger.Infof("[%s] invoke %s lambda hook : %s", region, phase, hook.FunctionName)
+
+		response, err := client.LambdaService.InvokeFunction(hook.FunctionName, hook.Qualifier, payload)
+		if err != nil {
+			d.notify(builder.NOTIFY_EVENT_FAILED, region, d.AsgNames[region], fmt.Sprintf(":x: Lambda hook %s failed in %s : %s", hook.FunctionName, region, err.Error()))
+			return fmt.Errorf("lambda hook %s failed in %s, so %s is aborted : %s", hook.FunctionName, region, phase, err.Error())
+		}
+
+		d.Logger.Infof("[%s] lambda hook %s is succeeded : %s", region, hook.FunctionName, string(response))
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	Logger "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testRegion = "ap-northeast-2"
+
+// lambdaRequest is the invocation received by the local stand-in of Lambda
+type lambdaRequest struct {
+	Path      string
+	Qualifier string
+	Payload   LambdaPayload
+}
+
+// newTestLambda starts the local stand-in of Lambda which responds with the handler
+// Invocations are recorded in requests.
+func newTestLambda(t *testing.T, requests *[]lambdaRequest, handler func(w http.ResponseWriter)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		request := lambdaRequest{Path: r.URL.Path, Qualifier: r.URL.Query().Get("Qualifier")}
+		if err := json.Unmarshal(body, &request.Payload); err != nil {
+			t.Errorf("payload is not JSON : %s", string(body))
+		}
+		*requests = append(*requests, request)
+
+		handler(w)
+	}))
+}
+
+// newTestClient creates Lambda client which invokes functions of the local stand-in
+func newTestClient(t *testing.T, server *httptest.Server) aws.AWSClient {
+	sess, err := session.NewSession(&awssdk.Config{
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+		MaxRetries:  awssdk.Int(0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return aws.AWSClient{
+		Region: testRegion,
+		LambdaService: aws.LambdaClient{
+			Client: lambda.New(sess, &awssdk.Config{
+				Region:   awssdk.String(testRegion),
+				Endpoint: awssdk.String(server.URL),
+			}),
+		},
+	}
+}
+
+func newTestDeployer(hooks builder.LambdaHooks) Deployer {
+	return Deployer{
+		Logger:    Logger.New(),
+		AwsConfig: builder.AWSConfig{Name: "hello"},
+		Stack: builder.Stack{
+			Stack:       "artd",
+			Env:         "dev",
+			LambdaHooks: hooks,
+		},
+		AsgNames: map[string]string{testRegion: "hello-artd_apnortheast2-v002"},
+		PrevAsgs: map[string][]string{testRegion: {"hello-artd_apnortheast2-v001"}},
+		Amis:     map[string]string{testRegion: "ami-01288945bd24ed49a"},
+	}
+}
+
+func TestRunLambdaHooksPayload(t *testing.T) {
+	requests := []lambdaRequest{}
+	server := newTestLambda(t, &requests, func(w http.ResponseWriter) {
+		fmt.Fprint(w, `{"ok":true}`)
+	})
+	defer server.Close()
+
+	d := newTestDeployer(builder.LambdaHooks{
+		PostHealthy: []builder.LambdaHook{
+			{FunctionName: "hello-cache-warmer", Qualifier: "live"},
+		},
+	})
+
+	// Release notes in base64 should be decoded for hooks
+	config := builder.Config{ReleaseNotesBase64: "aG90Zml4"}
+	if err := d.RunLambdaHooks(newTestClient(t, server), config, testRegion, builder.CALLBACK_PHASE_POST_HEALTHY); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 invocation, but got %d", len(requests))
+	}
+
+	request := requests[0]
+	if !strings.HasSuffix(request.Path, "/functions/hello-cache-warmer/invocations") {
+		t.Errorf("unexpected path of invocation : %s", request.Path)
+	}
+
+	if request.Qualifier != "live" {
+		t.Errorf("expected qualifier live, but got %s", request.Qualifier)
+	}
+
+	expected := LambdaPayload{
+		App:          "hello",
+		Stack:        "artd",
+		Env:          "dev",
+		Region:       testRegion,
+		Phase:        builder.CALLBACK_PHASE_POST_HEALTHY,
+		NewAsg:       "hello-artd_apnortheast2-v002",
+		PreviousAsgs: []string{"hello-artd_apnortheast2-v001"},
+		Ami:          "ami-01288945bd24ed49a",
+		ReleaseNotes: "hotfix",
+	}
+
+	if fmt.Sprintf("%+v", request.Payload) != fmt.Sprintf("%+v", expected) {
+		t.Errorf("expected payload %+v, but got %+v", expected, request.Payload)
+	}
+}
+
+func TestRunLambdaHooksFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+	}{
+		{
+			name: "function error",
+			handler: func(w http.ResponseWriter) {
+				w.Header().Set("X-Amz-Function-Error", "Unhandled")
+				fmt.Fprint(w, `{"errorMessage":"migration failed"}`)
+			},
+		},
+		{
+			name: "non-200 status",
+			handler: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusAccepted)
+			},
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter) {
+				w.Header().Set("X-Amzn-ErrorType", "ServiceException")
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"message":"internal error"}`)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := []lambdaRequest{}
+			server := newTestLambda(t, &requests, tt.handler)
+			defer server.Close()
+
+			// The second hook should not be invoked after the first one fails
+			d := newTestDeployer(builder.LambdaHooks{
+				PreDeploy: []builder.LambdaHook{
+					{FunctionName: "hello-db-migration"},
+					{FunctionName: "hello-announce"},
+				},
+			})
+
+			err := d.RunLambdaHooks(newTestClient(t, server), builder.Config{}, testRegion, builder.CALLBACK_PHASE_PRE_DEPLOY)
+			if err == nil {
+				t.Fatal("expected pre_deploy phase to fail, but it succeeded")
+			}
+
+			if !strings.Contains(err.Error(), "hello-db-migration") {
+				t.Errorf("expected error to name the failed function : %s", err.Error())
+			}
+
+			if len(requests) != 1 {
+				t.Errorf("expected 1 invocation, but got %d", len(requests))
+			}
+		})
+	}
+}
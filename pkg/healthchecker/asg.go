@@ -0,0 +1,29 @@
+package healthchecker
+
+import (
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// ASGChecker checks lifecycle and health status of instances in the autoscaling group
+// This is used for worker fleets without any load balancer.
+type ASGChecker struct{}
+
+func (a ASGChecker) Name() string {
+	return "autoscaling group"
+}
+
+func (a ASGChecker) Check(group *autoscaling.Group) ([]aws.HealthcheckHost, error) {
+	hosts := []aws.HealthcheckHost{}
+	for _, instance := range group.Instances {
+		hosts = append(hosts, aws.HealthcheckHost{
+			InstanceId:     *instance.InstanceId,
+			LifecycleState: *instance.LifecycleState,
+			TargetStatus:   *instance.LifecycleState,
+			HealthStatus:   *instance.HealthStatus,
+			Healthy:        *instance.LifecycleState == "InService" && *instance.HealthStatus == "Healthy",
+		})
+	}
+
+	return hosts, nil
+}
@@ -0,0 +1,60 @@
+package healthchecker
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"strings"
+)
+
+// ClassicELBChecker checks instance health of classic load balancers
+type ClassicELBChecker struct {
+	Client        aws.AWSClient
+	LoadBalancers []string
+}
+
+func NewClassicELBChecker(region builder.RegionConfig, client aws.AWSClient) ClassicELBChecker {
+	loadBalancers := region.LoadBalancers
+	if len(region.HealthcheckLB) > 0 && !tool.IsStringInArray(region.HealthcheckLB, loadBalancers) {
+		loadBalancers = append(loadBalancers, region.HealthcheckLB)
+	}
+
+	return ClassicELBChecker{
+		Client:        client,
+		LoadBalancers: loadBalancers,
+	}
+}
+
+func (c ClassicELBChecker) Name() string {
+	return fmt.Sprintf("classic load balancers[%s]", strings.Join(c.LoadBalancers, ","))
+}
+
+// Check returns health of instances
+// Instance is healthy only if it is in service in every load balancer.
+func (c ClassicELBChecker) Check(group *autoscaling.Group) ([]aws.HealthcheckHost, error) {
+	hosts := []aws.HealthcheckHost{}
+	for i, lb := range c.LoadBalancers {
+		lbHosts, err := c.Client.ClassicELBService.GetHostInLoadBalancer(group, lb)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			hosts = lbHosts
+			continue
+		}
+
+		for j := range hosts {
+			for _, host := range lbHosts {
+				if host.InstanceId == hosts[j].InstanceId {
+					hosts[j].TargetStatus = fmt.Sprintf("%s,%s", hosts[j].TargetStatus, host.TargetStatus)
+					hosts[j].Healthy = hosts[j].Healthy && host.Healthy
+				}
+			}
+		}
+	}
+
+	return hosts, nil
+}
@@ -0,0 +1,134 @@
+package healthchecker
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"strings"
+)
+
+// HealthChecker checks health of instances in the autoscaling group
+type HealthChecker interface {
+	// Name returns the name of health checker for logging
+	Name() string
+
+	// Check returns health of each instance in the autoscaling group
+	Check(group *autoscaling.Group) ([]aws.HealthcheckHost, error)
+}
+
+// NewHealthChecker creates health checker of the region with healthcheck configurations
+func NewHealthChecker(healthcheck builder.Healthcheck, region builder.RegionConfig, client aws.AWSClient) (HealthChecker, error) {
+	return newCombination(healthcheck.Operator, builder.GetHealthcheckProviders(healthcheck, region), region, client)
+}
+
+// newProvider creates health checker of single provider
+func newProvider(provider builder.HealthcheckProvider, region builder.RegionConfig, client aws.AWSClient) (HealthChecker, error) {
+	switch provider.Type {
+	case builder.HEALTHCHECK_TARGET_GROUP:
+		return NewTargetGroupChecker(region, client)
+	case builder.HEALTHCHECK_CLASSIC_ELB:
+		return NewClassicELBChecker(region, client), nil
+	case builder.HEALTHCHECK_ASG:
+		return ASGChecker{}, nil
+	case builder.HEALTHCHECK_HTTP:
+		return NewHTTPChecker(provider.HTTP, client.EC2Service.GetPrivateIpAddresses), nil
+	case builder.HEALTHCHECK_SSM:
+		return NewSSMChecker(provider.SSM, client), nil
+	case builder.HEALTHCHECK_COMBINATION:
+		return newCombination(provider.Operator, provider.Providers, region, client)
+	}
+
+	return nil, fmt.Errorf("not available healthcheck provider : %s", provider.Type)
+}
+
+// newCombination creates combined health checker of providers
+func newCombination(operator string, providers []builder.HealthcheckProvider, region builder.RegionConfig, client aws.AWSClient) (HealthChecker, error) {
+	checkers := []HealthChecker{}
+	for _, provider := range providers {
+		checker, err := newProvider(provider, region, client)
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, checker)
+	}
+
+	// No need to combine single checker
+	if len(checkers) == 1 {
+		return checkers[0], nil
+	}
+
+	if len(operator) == 0 {
+		operator = builder.OPERATOR_AND
+	}
+
+	return Combination{
+		Operator: operator,
+		Checkers: checkers,
+	}, nil
+}
+
+// Combination combines results of health checkers with and/or operator
+type Combination struct {
+	Operator string
+	Checkers []HealthChecker
+}
+
+func (c Combination) Name() string {
+	names := []string{}
+	for _, checker := range c.Checkers {
+		names = append(names, checker.Name())
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(names, fmt.Sprintf(" %s ", c.Operator)))
+}
+
+// Check returns health of instances combined
+// With and operator, instance is healthy only if all checkers say it is healthy.
+// With or operator, instance is healthy if any of checkers says it is healthy.
+func (c Combination) Check(group *autoscaling.Group) ([]aws.HealthcheckHost, error) {
+	ret := []aws.HealthcheckHost{}
+	index := map[string]int{}
+	// Number of checkers which reported each instance
+	seen := map[string]int{}
+
+	for _, checker := range c.Checkers {
+		hosts, err := checker.Check(group)
+		if err != nil {
+			return nil, err
+		}
+
+		reported := map[string]bool{}
+		for _, host := range hosts {
+			if !reported[host.InstanceId] {
+				reported[host.InstanceId] = true
+				seen[host.InstanceId]++
+			}
+
+			i, ok := index[host.InstanceId]
+			if !ok {
+				index[host.InstanceId] = len(ret)
+				ret = append(ret, host)
+				continue
+			}
+
+			ret[i].TargetStatus = fmt.Sprintf("%s,%s", ret[i].TargetStatus, host.TargetStatus)
+			if c.Operator == builder.OPERATOR_OR {
+				ret[i].Healthy = ret[i].Healthy || host.Healthy
+			} else {
+				ret[i].Healthy = ret[i].Healthy && host.Healthy
+			}
+		}
+	}
+
+	// With and operator, instance which is not checked by every checker is not healthy
+	if c.Operator != builder.OPERATOR_OR {
+		for i := range ret {
+			if seen[ret[i].InstanceId] != len(c.Checkers) {
+				ret[i].Healthy = false
+			}
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,195 @@
+package healthchecker
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	Logger "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	DEFAULT_HTTP_SCHEME                = "http"
+	DEFAULT_HTTP_PATH                  = "/"
+	DEFAULT_HTTP_EXPECTED_CODES        = []int{http.StatusOK}
+	DEFAULT_HTTP_CONSECUTIVE_SUCCESSES = int64(1)
+	DEFAULT_HTTP_TIMEOUT               = int64(5)
+	HTTP_BODY_READ_LIMIT               = int64(1 << 20)
+)
+
+// AddressResolver returns addresses of instances to probe
+type AddressResolver func(instanceIds []string) (map[string]string, error)
+
+// HTTPChecker probes HTTP(S) endpoint of each instance
+// Instance is healthy only if the probe succeeds consecutively as many as configured.
+type HTTPChecker struct {
+	Config    builder.HTTPHealthcheck
+	Client    *http.Client
+	Resolve   AddressResolver
+	bodyMatch *regexp.Regexp
+	successes map[string]int64
+}
+
+func NewHTTPChecker(config builder.HTTPHealthcheck, resolve AddressResolver) HTTPChecker {
+	if len(config.Scheme) == 0 {
+		config.Scheme = DEFAULT_HTTP_SCHEME
+	}
+
+	if len(config.Path) == 0 {
+		config.Path = DEFAULT_HTTP_PATH
+	}
+
+	if len(config.ExpectedCodes) == 0 {
+		config.ExpectedCodes = DEFAULT_HTTP_EXPECTED_CODES
+	}
+
+	if config.ConsecutiveSuccesses == 0 {
+		config.ConsecutiveSuccesses = DEFAULT_HTTP_CONSECUTIVE_SUCCESSES
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = DEFAULT_HTTP_TIMEOUT
+	}
+
+	var bodyMatch *regexp.Regexp
+	if len(config.BodyMatch) > 0 {
+		// body_match is already validated with the manifest
+		bodyMatch = regexp.MustCompile(config.BodyMatch)
+	}
+
+	return HTTPChecker{
+		Config: config,
+		Client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+			},
+		},
+		Resolve:   resolve,
+		bodyMatch: bodyMatch,
+		successes: map[string]int64{},
+	}
+}
+
+func (h HTTPChecker) Name() string {
+	return fmt.Sprintf("http[%s://:%d%s]", h.Config.Scheme, h.Config.Port, h.Config.Path)
+}
+
+// Check probes instances of the autoscaling group
+func (h HTTPChecker) Check(group *autoscaling.Group) ([]aws.HealthcheckHost, error) {
+	instanceIds := []string{}
+	for _, instance := range group.Instances {
+		instanceIds = append(instanceIds, *instance.InstanceId)
+	}
+
+	statuses, err := h.probeInstances(instanceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []aws.HealthcheckHost{}
+	for _, instance := range group.Instances {
+		hosts = append(hosts, aws.HealthcheckHost{
+			InstanceId:     *instance.InstanceId,
+			LifecycleState: *instance.LifecycleState,
+			TargetStatus:   fmt.Sprintf("%s(%d/%d)", statuses[*instance.InstanceId], h.successes[*instance.InstanceId], h.Config.ConsecutiveSuccesses),
+			HealthStatus:   *instance.HealthStatus,
+			Healthy:        *instance.LifecycleState == "InService" && h.successes[*instance.InstanceId] >= h.Config.ConsecutiveSuccesses,
+		})
+	}
+
+	return hosts, nil
+}
+
+// CheckInstances probes instances regardless of their lifecycle state
+func (h HTTPChecker) CheckInstances(instanceIds []string) (map[string]bool, error) {
+	if _, err := h.probeInstances(instanceIds); err != nil {
+		return nil, err
+	}
+
+	ret := map[string]bool{}
+	for _, instanceId := range instanceIds {
+		ret[instanceId] = h.successes[instanceId] >= h.Config.ConsecutiveSuccesses
+	}
+
+	return ret, nil
+}
+
+// probeInstances probes instances and counts consecutive successes
+// It returns the status of probe for each instance.
+func (h HTTPChecker) probeInstances(instanceIds []string) (map[string]string, error) {
+	addresses, err := h.Resolve(instanceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := map[string]string{}
+	for _, instanceId := range instanceIds {
+		address, ok := addresses[instanceId]
+		if !ok {
+			// Unresolved probe breaks consecutive successes as failure does
+			statuses[instanceId] = "unresolved"
+			h.successes[instanceId] = 0
+			continue
+		}
+
+		status, err := h.Probe(address)
+		if err != nil {
+			Logger.Debugf("http healthcheck failed for %s(%s) : %s", instanceId, address, err.Error())
+			h.successes[instanceId] = 0
+		} else {
+			h.successes[instanceId]++
+		}
+		statuses[instanceId] = status
+	}
+
+	return statuses, nil
+}
+
+// Probe sends a request to the address and checks the response
+// It returns the status of the response even if the check fails.
+func (h HTTPChecker) Probe(address string) (string, error) {
+	url := fmt.Sprintf("%s://%s%s", h.Config.Scheme, net.JoinHostPort(address, strconv.FormatInt(h.Config.Port, 10)), h.Config.Path)
+
+	resp, err := h.Client.Get(url)
+	if err != nil {
+		return "unreachable", err
+	}
+	defer resp.Body.Close()
+
+	status := strconv.Itoa(resp.StatusCode)
+	if !isExpectedCode(resp.StatusCode, h.Config.ExpectedCodes) {
+		return status, fmt.Errorf("unexpected status code from %s : %d", url, resp.StatusCode)
+	}
+
+	if h.bodyMatch != nil {
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, HTTP_BODY_READ_LIMIT))
+		if err != nil {
+			return status, err
+		}
+
+		if !h.bodyMatch.Match(body) {
+			return status, fmt.Errorf("response body from %s does not match %s", url, h.Config.BodyMatch)
+		}
+	}
+
+	return status, nil
+}
+
+// isExpectedCode checks if status code is one of expected codes
+func isExpectedCode(code int, expected []int) bool {
+	for _, e := range expected {
+		if e == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package healthchecker
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+const testInstanceId = "i-0123456789abcdef0"
+
+// newTestChecker creates HTTP checker which probes the local server instead of instances
+func newTestChecker(t *testing.T, server *httptest.Server, config builder.HTTPHealthcheck) HTTPChecker {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Port, err = strconv.ParseInt(port, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewHTTPChecker(config, func(instanceIds []string) (map[string]string, error) {
+		ret := map[string]string{}
+		for _, instanceId := range instanceIds {
+			ret[instanceId] = host
+		}
+		return ret, nil
+	})
+}
+
+func newTestServer(code int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestHTTPCheckerExpectedCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		expected []int
+		healthy  bool
+	}{
+		{name: "default accepts 200", code: http.StatusOK, healthy: true},
+		{name: "default rejects 204", code: http.StatusNoContent, healthy: false},
+		{name: "configured code", code: http.StatusNoContent, expected: []int{http.StatusOK, http.StatusNoContent}, healthy: true},
+		{name: "server error", code: http.StatusInternalServerError, expected: []int{http.StatusOK}, healthy: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(tt.code, "")
+			defer server.Close()
+
+			checker := newTestChecker(t, server, builder.HTTPHealthcheck{ExpectedCodes: tt.expected})
+			ret, err := checker.CheckInstances([]string{testInstanceId})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ret[testInstanceId] != tt.healthy {
+				t.Errorf("expected healthy=%t for status %d, but got %t", tt.healthy, tt.code, ret[testInstanceId])
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerBodyMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		bodyMatch string
+		healthy   bool
+	}{
+		{name: "no body match", body: "anything", healthy: true},
+		{name: "matched", body: `{"status":"UP"}`, bodyMatch: `"status":\s*"UP"`, healthy: true},
+		{name: "not matched", body: `{"status":"DOWN"}`, bodyMatch: `"status":\s*"UP"`, healthy: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(http.StatusOK, tt.body)
+			defer server.Close()
+
+			checker := newTestChecker(t, server, builder.HTTPHealthcheck{BodyMatch: tt.bodyMatch})
+			ret, err := checker.CheckInstances([]string{testInstanceId})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ret[testInstanceId] != tt.healthy {
+				t.Errorf("expected healthy=%t for body %s, but got %t", tt.healthy, tt.body, ret[testInstanceId])
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerConsecutiveSuccesses(t *testing.T) {
+	var code int32 = http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&code)))
+	}))
+	defer server.Close()
+
+	checker := newTestChecker(t, server, builder.HTTPHealthcheck{ConsecutiveSuccesses: 2})
+	group := &autoscaling.Group{
+		Instances: []*autoscaling.Instance{
+			{
+				InstanceId:     aws.String(testInstanceId),
+				LifecycleState: aws.String("InService"),
+				HealthStatus:   aws.String("Healthy"),
+			},
+		},
+	}
+
+	// Successes accumulate, and a failure resets them
+	steps := []struct {
+		code    int32
+		healthy bool
+	}{
+		{code: http.StatusOK, healthy: false},
+		{code: http.StatusOK, healthy: true},
+		{code: http.StatusOK, healthy: true},
+		{code: http.StatusServiceUnavailable, healthy: false},
+		{code: http.StatusOK, healthy: false},
+		{code: http.StatusOK, healthy: true},
+	}
+
+	for i, step := range steps {
+		atomic.StoreInt32(&code, step.code)
+
+		hosts, err := checker.Check(group)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(hosts) != 1 {
+			t.Fatalf("expected 1 host, but got %d", len(hosts))
+		}
+
+		if hosts[0].Healthy != step.healthy {
+			t.Errorf("step %d: expected healthy=%t with status %d, but got %t(%s)", i, step.healthy, step.code, hosts[0].Healthy, hosts[0].TargetStatus)
+		}
+	}
+}
+
+func TestHTTPCheckerUnresolvedResetsSuccesses(t *testing.T) {
+	server := newTestServer(http.StatusOK, "")
+	defer server.Close()
+
+	checker := newTestChecker(t, server, builder.HTTPHealthcheck{ConsecutiveSuccesses: 2})
+	resolve := checker.Resolve
+
+	// Address of the instance is not resolved in the second probe
+	steps := []struct {
+		resolved bool
+		healthy  bool
+	}{
+		{resolved: true, healthy: false},
+		{resolved: false, healthy: false},
+		{resolved: true, healthy: false},
+		{resolved: true, healthy: true},
+	}
+
+	for i, step := range steps {
+		checker.Resolve = resolve
+		if !step.resolved {
+			checker.Resolve = func(instanceIds []string) (map[string]string, error) {
+				return map[string]string{}, nil
+			}
+		}
+
+		ret, err := checker.CheckInstances([]string{testInstanceId})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ret[testInstanceId] != step.healthy {
+			t.Errorf("step %d: expected healthy=%t, but got %t", i, step.healthy, ret[testInstanceId])
+		}
+	}
+}
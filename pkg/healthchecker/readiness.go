@@ -0,0 +1,29 @@
+package healthchecker
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+)
+
+// ReadinessChecker checks if instances waiting in launch lifecycle hook are ready
+// Unlike HealthChecker, instances are checked regardless of their lifecycle state.
+type ReadinessChecker interface {
+	// Name returns the name of readiness checker for logging
+	Name() string
+
+	// CheckInstances returns readiness of each instance
+	CheckInstances(instanceIds []string) (map[string]bool, error)
+}
+
+// NewReadinessChecker creates readiness checker of launch lifecycle hook
+func NewReadinessChecker(readiness builder.HealthcheckProvider, client aws.AWSClient) (ReadinessChecker, error) {
+	switch readiness.Type {
+	case builder.HEALTHCHECK_HTTP:
+		return NewHTTPChecker(readiness.HTTP, client.EC2Service.GetPrivateIpAddresses), nil
+	case builder.HEALTHCHECK_SSM:
+		return NewSSMChecker(readiness.SSM, client), nil
+	}
+
+	return nil, fmt.Errorf("not available readiness check : %s", readiness.Type)
+}
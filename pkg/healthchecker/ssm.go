@@ -0,0 +1,110 @@
+package healthchecker
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	Logger "github.com/sirupsen/logrus"
+	"strings"
+)
+
+var (
+	DEFAULT_SSM_HEALTHCHECK_TIMEOUT = int64(30)
+)
+
+// SSMChecker runs commands in each instance with SSM Run Command
+// Instance is healthy only if commands exit with zero.
+type SSMChecker struct {
+	Config builder.SSMHealthcheck
+	Client aws.AWSClient
+}
+
+func NewSSMChecker(config builder.SSMHealthcheck, client aws.AWSClient) SSMChecker {
+	if config.Timeout == 0 {
+		config.Timeout = DEFAULT_SSM_HEALTHCHECK_TIMEOUT
+	}
+
+	return SSMChecker{
+		Config: config,
+		Client: client,
+	}
+}
+
+func (s SSMChecker) Name() string {
+	return fmt.Sprintf("ssm[%s]", strings.Join(s.Config.Commands, " && "))
+}
+
+// Check runs commands in instances which are in service, and waits for the results until timeout
+func (s SSMChecker) Check(group *autoscaling.Group) ([]aws.HealthcheckHost, error) {
+	// Instances are not registered to SSM while launching, so commands are sent to instances in service only.
+	instanceIds := []string{}
+	for _, instance := range group.Instances {
+		if *instance.LifecycleState == "InService" {
+			instanceIds = append(instanceIds, *instance.InstanceId)
+		}
+	}
+
+	commandIds, invocations := s.runCommands(instanceIds)
+
+	hosts := []aws.HealthcheckHost{}
+	for _, instance := range group.Instances {
+		status := *instance.LifecycleState
+		healthy := false
+		if invocation, ok := invocations[*instance.InstanceId]; ok {
+			status = fmt.Sprintf("%s(%d)", invocation.Status, invocation.ResponseCode)
+			healthy = aws.IsCommandSucceeded(invocation)
+		} else if _, ok := commandIds[*instance.InstanceId]; !ok && status == "InService" {
+			status = "unregistered"
+		}
+
+		hosts = append(hosts, aws.HealthcheckHost{
+			InstanceId:     *instance.InstanceId,
+			LifecycleState: *instance.LifecycleState,
+			TargetStatus:   status,
+			HealthStatus:   *instance.HealthStatus,
+			Healthy:        healthy,
+		})
+	}
+
+	return hosts, nil
+}
+
+// CheckInstances runs commands in instances regardless of their lifecycle state
+func (s SSMChecker) CheckInstances(instanceIds []string) (map[string]bool, error) {
+	_, invocations := s.runCommands(instanceIds)
+
+	ret := map[string]bool{}
+	for _, instanceId := range instanceIds {
+		invocation, ok := invocations[instanceId]
+		ret[instanceId] = ok && aws.IsCommandSucceeded(invocation)
+	}
+
+	return ret, nil
+}
+
+// runCommands sends commands to each instance, and waits for the results until timeout
+// Instances to which commands cannot be sent are not in the results.
+func (s SSMChecker) runCommands(instanceIds []string) (map[string]string, map[string]aws.CommandInvocation) {
+	commandIds := map[string]string{}
+	for _, instanceId := range instanceIds {
+		commandId, err := s.Client.SSMService.SendCommandWithComment(
+			aws.MakeStringArrayToAwsStrings([]string{instanceId}),
+			aws.MakeStringArrayToAwsStrings(s.Config.Commands),
+			"goployer healthcheck",
+			s.Config.Timeout,
+		)
+		if err != nil {
+			Logger.Debugf("failed to send healthcheck command to %s : %s", instanceId, err.Error())
+			continue
+		}
+		commandIds[instanceId] = commandId
+	}
+
+	invocations := s.Client.SSMService.WaitCommandInvocations(commandIds, s.Config.Timeout)
+	for _, invocation := range invocations {
+		aws.LogCommandInvocation("healthcheck", invocation)
+	}
+
+	return commandIds, invocations
+}
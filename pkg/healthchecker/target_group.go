@@ -0,0 +1,71 @@
+package healthchecker
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"strings"
+)
+
+// TargetGroupChecker checks health of instances in all target groups attached to the autoscaling group
+type TargetGroupChecker struct {
+	Client          aws.AWSClient
+	TargetGroups    []string
+	TargetGroupArns []string
+}
+
+func NewTargetGroupChecker(region builder.RegionConfig, client aws.AWSClient) (TargetGroupChecker, error) {
+	targetGroups := region.TargetGroups
+	if len(region.HealthcheckTargetGroup) > 0 && !tool.IsStringInArray(region.HealthcheckTargetGroup, targetGroups) {
+		targetGroups = append(targetGroups, region.HealthcheckTargetGroup)
+	}
+
+	arns := []string{}
+	for _, tg := range targetGroups {
+		arn, err := client.ELBService.FindTargetGroupARN(tg)
+		if err != nil {
+			return TargetGroupChecker{}, err
+		}
+		arns = append(arns, arn)
+	}
+
+	return TargetGroupChecker{
+		Client:          client,
+		TargetGroups:    targetGroups,
+		TargetGroupArns: arns,
+	}, nil
+}
+
+func (t TargetGroupChecker) Name() string {
+	return fmt.Sprintf("target groups[%s]", strings.Join(t.TargetGroups, ","))
+}
+
+// Check returns health of instances
+// Instance is healthy only if it is healthy in every target group.
+func (t TargetGroupChecker) Check(group *autoscaling.Group) ([]aws.HealthcheckHost, error) {
+	hosts := []aws.HealthcheckHost{}
+	for i := range t.TargetGroupArns {
+		targetHosts, err := t.Client.ELBService.GetHostInTarget(group, &t.TargetGroupArns[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			hosts = targetHosts
+			continue
+		}
+
+		for j := range hosts {
+			for _, host := range targetHosts {
+				if host.InstanceId == hosts[j].InstanceId {
+					hosts[j].TargetStatus = fmt.Sprintf("%s,%s", hosts[j].TargetStatus, host.TargetStatus)
+					hosts[j].Healthy = hosts[j].Healthy && host.Healthy
+				}
+			}
+		}
+	}
+
+	return hosts, nil
+}
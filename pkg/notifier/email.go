@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailNotifier sends mails of events with SMTP
+// STARTTLS is used if the server supports it.
+type EmailNotifier struct {
+	Config builder.EmailNotification
+	Addr   string
+	Auth   smtp.Auth
+}
+
+func NewEmailNotifier(config builder.EmailNotification) EmailNotifier {
+	var auth smtp.Auth
+	if len(config.Username) > 0 {
+		auth = smtp.PlainAuth("", config.Username, os.Getenv(config.PasswordEnv), config.Host)
+	}
+
+	return EmailNotifier{
+		Config: config,
+		Addr:   net.JoinHostPort(config.Host, strconv.FormatInt(builder.GetSmtpPort(config), 10)),
+		Auth:   auth,
+	}
+}
+
+func (e EmailNotifier) Name() string {
+	return fmt.Sprintf("email[%s]", strings.Join(e.Config.To, ","))
+}
+
+// Notify sends the mail like smtp.SendMail, but within NOTIFY_TIMEOUT
+// Hung SMTP server should not block the deployment.
+func (e EmailNotifier) Notify(event Event) error {
+	conn, err := net.DialTimeout("tcp", e.Addr, NOTIFY_TIMEOUT)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(NOTIFY_TIMEOUT)); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, e.Config.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: e.Config.Host}); err != nil {
+			return err
+		}
+	}
+
+	if e.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server does not support AUTH : %s", e.Addr)
+		}
+
+		if err := c.Auth(e.Auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(e.Config.From); err != nil {
+		return err
+	}
+
+	for _, to := range e.Config.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(makeMail(e.Config.From, e.Config.To, event)); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// makeMail makes a plain text mail of the event
+func makeMail(from string, to []string, event Event) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: [goployer] %s\r\n", title(event))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+
+	for _, line := range strings.Split(event.Message, "\n") {
+		fmt.Fprintf(&b, "%s\r\n", line)
+	}
+	b.WriteString("\r\n")
+
+	for _, f := range facts(event) {
+		fmt.Fprintf(&b, "%s: %s\r\n", f.Name, f.Value)
+	}
+
+	return b.Bytes()
+}
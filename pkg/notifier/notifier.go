@@ -0,0 +1,186 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	Logger "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+var (
+	NOTIFY_TIMEOUT = 10 * time.Second
+)
+
+// Event is a structured event of deployment
+type Event struct {
+	Type      string    `json:"type"`
+	App       string    `json:"app"`
+	Stack     string    `json:"stack"`
+	Env       string    `json:"env"`
+	Region    string    `json:"region,omitempty"`
+	Asg       string    `json:"asg,omitempty"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+	// Release notes are set only for events of the whole deployment
+	ReleaseNotes string `json:"release_notes,omitempty"`
+}
+
+// Notifier sends events of deployment to a destination
+type Notifier interface {
+	Name() string
+	Notify(event Event) error
+}
+
+// Notifiers sends events to all notifiers
+// Failure of a notifier does not stop sending events to others.
+type Notifiers []Notifier
+
+// fact is a pair of name and value which describes the event
+type fact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// filtered sends only events of given types
+type filtered struct {
+	Notifier
+	events []string
+}
+
+// NewEvent creates an event of the type
+func NewEvent(eventType, app, stack, env, region, asg, message string) Event {
+	return Event{
+		Type:      eventType,
+		App:       app,
+		Stack:     stack,
+		Env:       env,
+		Region:    region,
+		Asg:       asg,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
+
+// New creates notifiers of the stack
+// If no notification is configured, slack with SLACK_TOKEN and SLACK_CHANNEL is used for backward compatibility.
+func New(stack builder.Stack, slackOff bool) Notifiers {
+	notifications := stack.Notifications
+	if !builder.HasNotifications(notifications) {
+		notifications.Slack = []builder.SlackNotification{{}}
+	}
+
+	notifiers := Notifiers{}
+	for _, config := range notifications.Slack {
+		s := NewSlackNotifier(config, slackOff)
+		if !s.Client.ValidClient() {
+			if !slackOff {
+				Logger.Warnf("no slack variables exists for stack %s. [ %s, %s ]", stack.Stack, tool.SLACK_TOKEN, tool.SLACK_CHANNEL)
+			}
+			continue
+		}
+		notifiers = append(notifiers, withEvents(s, config.Events))
+	}
+
+	for _, config := range notifications.Webhooks {
+		notifiers = append(notifiers, withEvents(NewWebhookNotifier(config), config.Events))
+	}
+
+	for _, config := range notifications.Teams {
+		notifiers = append(notifiers, withEvents(NewTeamsNotifier(config), config.Events))
+	}
+
+	for _, config := range notifications.Email {
+		notifiers = append(notifiers, withEvents(NewEmailNotifier(config), config.Events))
+	}
+
+	return notifiers
+}
+
+// Merge merges notifiers of stacks
+// The same destination appears only once, so events of the whole deployment are not sent twice.
+func Merge(notifiers ...Notifiers) Notifiers {
+	names := []string{}
+	ret := Notifiers{}
+	for _, ns := range notifiers {
+		for _, n := range ns {
+			if tool.IsStringInArray(n.Name(), names) {
+				continue
+			}
+			names = append(names, n.Name())
+			ret = append(ret, n)
+		}
+	}
+
+	return ret
+}
+
+func (ns Notifiers) Name() string {
+	names := []string{}
+	for _, n := range ns {
+		names = append(names, n.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Notify sends the event to all notifiers
+func (ns Notifiers) Notify(event Event) error {
+	errs := []string{}
+	for _, n := range ns {
+		if err := n.Notify(event); err != nil {
+			errs = append(errs, fmt.Sprintf("%s : %s", n.Name(), err.Error()))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send %s event : %s", event.Type, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// withEvents filters events if types of events are specified
+func withEvents(n Notifier, events []string) Notifier {
+	if len(events) == 0 {
+		return n
+	}
+
+	return filtered{Notifier: n, events: events}
+}
+
+func (f filtered) Name() string {
+	return fmt.Sprintf("%s(%s)", f.Notifier.Name(), strings.Join(f.events, ","))
+}
+
+func (f filtered) Notify(event Event) error {
+	if !tool.IsStringInArray(event.Type, f.events) {
+		return nil
+	}
+
+	return f.Notifier.Notify(event)
+}
+
+// title returns a short summary of the event
+func title(event Event) string {
+	return fmt.Sprintf("[%s] %s/%s : %s", event.Env, event.App, event.Stack, event.Type)
+}
+
+// facts returns details of the event except message
+func facts(event Event) []fact {
+	ret := []fact{
+		{Name: "App", Value: event.App},
+		{Name: "Stack", Value: event.Stack},
+		{Name: "Env", Value: event.Env},
+	}
+
+	if len(event.Region) > 0 {
+		ret = append(ret, fact{Name: "Region", Value: event.Region})
+	}
+
+	if len(event.Asg) > 0 {
+		ret = append(ret, fact{Name: "Autoscaling Group", Value: event.Asg})
+	}
+
+	return append(ret, fact{Name: "Time", Value: event.Timestamp.Format(time.RFC3339)})
+}
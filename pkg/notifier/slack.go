@@ -0,0 +1,213 @@
+package notifier
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	"github.com/slack-go/slack"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	SLACK_SECTION_TEXT_LIMIT = 3000
+
+	slackThreads     = map[string]*slackThread{}
+	slackThreadsLock sync.Mutex
+
+	slackDeploymentStatus = map[string]string{
+		builder.NOTIFY_EVENT_STARTED: ":arrow_forward: In progress",
+		builder.NOTIFY_EVENT_FAILED:  ":x: Failed",
+		builder.NOTIFY_EVENT_DONE:    ":100: Done",
+	}
+
+	slackRegionStatus = map[string]string{
+		builder.NOTIFY_EVENT_STARTED:         ":arrow_forward: Preparing",
+		builder.NOTIFY_EVENT_REGION_DEPLOYED: ":rocket: Deployed",
+		builder.NOTIFY_EVENT_WAITING:         ":hourglass_flowing_sand: Waiting",
+		builder.NOTIFY_EVENT_HEALTHY:         ":white_check_mark: Healthy",
+		builder.NOTIFY_EVENT_CLEANUP:         ":broom: Cleaning up",
+		builder.NOTIFY_EVENT_FAILED:          ":x: Failed",
+	}
+)
+
+// SlackNotifier keeps one summary message of the deployment in the channel
+// The summary is updated in place with status of each region, and messages of events are replied in its thread.
+type SlackNotifier struct {
+	Client tool.Slack
+	thread *slackThread
+}
+
+// slackThread is the summary message of the deployment
+// Notifiers of all stacks share the summary if they post to the same channel.
+type slackThread struct {
+	lock           sync.Mutex
+	channelId      string
+	ts             string
+	title          string
+	status         string
+	regions        []string
+	regionStatuses map[string]string
+	releaseNotes   string
+}
+
+// NewSlackNotifier creates slack notifier
+// Channel in the manifest overrides SLACK_CHANNEL.
+func NewSlackNotifier(config builder.SlackNotification, slackOff bool) SlackNotifier {
+	client := tool.NewSlackClient(slackOff)
+	if len(config.Channel) > 0 {
+		client.ChannelId = config.Channel
+	}
+
+	return SlackNotifier{
+		Client: client,
+		thread: getSlackThread(client.ChannelId),
+	}
+}
+
+// getSlackThread returns the summary of the channel
+func getSlackThread(channel string) *slackThread {
+	slackThreadsLock.Lock()
+	defer slackThreadsLock.Unlock()
+
+	if _, ok := slackThreads[channel]; !ok {
+		slackThreads[channel] = &slackThread{
+			status:         slackDeploymentStatus[builder.NOTIFY_EVENT_STARTED],
+			regionStatuses: map[string]string{},
+		}
+	}
+
+	return slackThreads[channel]
+}
+
+func (s SlackNotifier) Name() string {
+	return fmt.Sprintf("slack[%s]", s.Client.ChannelId)
+}
+
+// Notify posts the summary with the first event, and updates it only if status is changed
+// Message of every event goes to the thread of the summary, so the channel is not flooded while polling.
+func (s SlackNotifier) Notify(event Event) error {
+	if !s.Client.ValidClient() {
+		return nil
+	}
+
+	t := s.thread
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	changed := t.apply(event)
+	if len(t.ts) == 0 {
+		channelId, ts, err := s.Client.PostMessage(slack.MsgOptionBlocks(t.blocks(s.Client)...), slack.MsgOptionText(t.title, false))
+		if err != nil {
+			return err
+		}
+		t.channelId, t.ts = channelId, ts
+	} else if changed {
+		if err := s.Client.UpdateMessage(t.channelId, t.ts, slack.MsgOptionBlocks(t.blocks(s.Client)...), slack.MsgOptionText(t.title, false)); err != nil {
+			return err
+		}
+	}
+
+	message := event.Message
+	if event.Type == builder.NOTIFY_EVENT_DONE && len(event.ReleaseNotes) > 0 {
+		message = fmt.Sprintf("%s\n%s", message, releaseNotesText(event.ReleaseNotes))
+	}
+
+	return s.Client.SendThreadMessage(t.channelId, t.ts, message, event.Env)
+}
+
+// apply applies the event to the summary, and returns whether the summary is changed
+func (t *slackThread) apply(event Event) bool {
+	before := t.text()
+
+	if len(t.title) == 0 {
+		t.title = fmt.Sprintf("*%s* deployment to `%s`", event.App, event.Env)
+	}
+
+	if len(event.ReleaseNotes) > 0 {
+		t.releaseNotes = event.ReleaseNotes
+	}
+
+	if len(event.Region) == 0 {
+		// Events without region are of the whole deployment or the stack
+		if status, ok := slackDeploymentStatus[event.Type]; ok {
+			t.status = status
+		}
+		return before != t.text()
+	}
+
+	status, ok := slackRegionStatus[event.Type]
+	if !ok {
+		return before != t.text()
+	}
+
+	if len(event.Asg) > 0 {
+		status = fmt.Sprintf("%s `%s`", status, event.Asg)
+	}
+
+	key := fmt.Sprintf("%s / %s", event.Stack, event.Region)
+	if _, ok := t.regionStatuses[key]; !ok {
+		t.regions = append(t.regions, key)
+	}
+	t.regionStatuses[key] = status
+
+	return before != t.text()
+}
+
+// text returns all of the summary to detect changes
+func (t *slackThread) text() string {
+	lines := []string{t.title, t.status, t.releaseNotes}
+	for _, region := range t.regions {
+		lines = append(lines, region, t.regionStatuses[region])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// blocks makes Block Kit blocks of the summary
+func (t *slackThread) blocks(s tool.Slack) []slack.Block {
+	blocks := []slack.Block{
+		s.CreateSimpleSection(fmt.Sprintf("%s\n*Status* : %s", t.title, t.status)),
+	}
+
+	if len(t.regions) > 0 {
+		lines := []string{}
+		for _, region := range t.regions {
+			lines = append(lines, fmt.Sprintf("• *%s* : %s", region, t.regionStatuses[region]))
+		}
+		blocks = append(blocks, s.CreateDividerSection(), s.CreateSimpleSection(truncate(strings.Join(lines, "\n"), SLACK_SECTION_TEXT_LIMIT)))
+	}
+
+	if len(t.releaseNotes) > 0 {
+		blocks = append(blocks, s.CreateDividerSection(), s.CreateSimpleSection(releaseNotesText(t.releaseNotes)))
+	}
+
+	return blocks
+}
+
+// releaseNotesText links release notes if they are an url, or quotes them
+func releaseNotesText(releaseNotes string) string {
+	if u, err := url.Parse(releaseNotes); err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0 {
+		return fmt.Sprintf(":memo: <%s|Release notes>", releaseNotes)
+	}
+
+	title := ":memo: *Release notes*\n"
+	return title + truncate(releaseNotes, SLACK_SECTION_TEXT_LIMIT-len(title))
+}
+
+// truncate cuts the text to the limit of Block Kit without breaking multibyte characters
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	end := 0
+	for i := range text {
+		if i > limit-3 {
+			break
+		}
+		end = i
+	}
+
+	return text[:end] + "..."
+}
@@ -0,0 +1,72 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"net/http"
+)
+
+var (
+	DEFAULT_TEAMS_COLOR = "663399"
+
+	teamsColorMapping = map[string]string{
+		builder.NOTIFY_EVENT_HEALTHY: "36a64f",
+		builder.NOTIFY_EVENT_DONE:    "36a64f",
+		builder.NOTIFY_EVENT_FAILED:  "ff0000",
+	}
+)
+
+// TeamsNotifier posts message cards to the incoming webhook of Microsoft Teams connector
+type TeamsNotifier struct {
+	Config builder.TeamsNotification
+	Client *http.Client
+}
+
+// teamsMessageCard is the legacy actionable message card which connectors accept
+type teamsMessageCard struct {
+	Type       string         `json:"@type"`
+	Context    string         `json:"@context"`
+	Summary    string         `json:"summary"`
+	ThemeColor string         `json:"themeColor"`
+	Title      string         `json:"title"`
+	Text       string         `json:"text"`
+	Sections   []teamsSection `json:"sections"`
+}
+
+type teamsSection struct {
+	Facts []fact `json:"facts"`
+}
+
+func NewTeamsNotifier(config builder.TeamsNotification) TeamsNotifier {
+	return TeamsNotifier{
+		Config: config,
+		Client: &http.Client{Timeout: NOTIFY_TIMEOUT},
+	}
+}
+
+func (t TeamsNotifier) Name() string {
+	return fmt.Sprintf("teams[%s]", maskUrl(t.Config.WebhookUrl))
+}
+
+func (t TeamsNotifier) Notify(event Event) error {
+	color, ok := teamsColorMapping[event.Type]
+	if !ok {
+		color = DEFAULT_TEAMS_COLOR
+	}
+
+	body, err := json.Marshal(teamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    title(event),
+		ThemeColor: color,
+		Title:      title(event),
+		Text:       event.Message,
+		Sections:   []teamsSection{{Facts: facts(event)}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return postJSON(t.Client, t.Config.WebhookUrl, body, nil)
+}
@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+var (
+	WEBHOOK_EVENT_HEADER     = "X-Goployer-Event"
+	WEBHOOK_SIGNATURE_HEADER = "X-Goployer-Signature"
+)
+
+// WebhookNotifier posts events in JSON to the url
+// If secret is set, body is signed with HMAC-SHA256 and the signature is in X-Goployer-Signature header as sha256=<hex>.
+type WebhookNotifier struct {
+	Config builder.WebhookNotification
+	Client *http.Client
+	secret string
+}
+
+func NewWebhookNotifier(config builder.WebhookNotification) WebhookNotifier {
+	secret := ""
+	if len(config.SecretEnv) > 0 {
+		secret = os.Getenv(config.SecretEnv)
+	}
+
+	return WebhookNotifier{
+		Config: config,
+		Client: &http.Client{Timeout: NOTIFY_TIMEOUT},
+		secret: secret,
+	}
+}
+
+func (w WebhookNotifier) Name() string {
+	return fmt.Sprintf("webhook[%s]", maskUrl(w.Config.Url))
+}
+
+func (w WebhookNotifier) Notify(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{WEBHOOK_EVENT_HEADER: event.Type}
+	for k, v := range w.Config.Headers {
+		headers[k] = v
+	}
+
+	if len(w.secret) > 0 {
+		headers[WEBHOOK_SIGNATURE_HEADER] = fmt.Sprintf("sha256=%s", Sign(w.secret, body))
+	}
+
+	return postJSON(w.Client, w.Config.Url, body, headers)
+}
+
+// Sign returns HMAC-SHA256 signature of the body in hex
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postJSON posts the body in JSON, and checks the status code of response
+func postJSON(client *http.Client, rawUrl string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(http.MethodPost, rawUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// error of http client contains url, so only the underlying error is kept
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return fmt.Errorf("failed to post to %s : %w", hostOf(rawUrl), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code from %s : %d", hostOf(rawUrl), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// maskUrl returns the host and a short hash of the url
+// Path or query of webhook url could contain secrets, but different urls should be distinguished.
+func maskUrl(rawUrl string) string {
+	sum := sha256.Sum256([]byte(rawUrl))
+	return fmt.Sprintf("%s#%s", hostOf(rawUrl), hex.EncodeToString(sum[:4]))
+}
+
+// hostOf returns host of the url
+func hostOf(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "invalid url"
+	}
+	return u.Host
+}
@@ -5,8 +5,10 @@ import (
 	"github.com/DevopsArtFactory/goployer/pkg/builder"
 	"github.com/DevopsArtFactory/goployer/pkg/collector"
 	"github.com/DevopsArtFactory/goployer/pkg/deployer"
+	"github.com/DevopsArtFactory/goployer/pkg/notifier"
 	"github.com/DevopsArtFactory/goployer/pkg/tool"
 	Logger "github.com/sirupsen/logrus"
+	"strings"
 	"time"
 
 	"os"
@@ -16,7 +18,8 @@ type Runner struct {
 	Logger    *Logger.Logger
 	Builder   builder.Builder
 	Collector collector.Collector
-	Slacker   tool.Slack
+	// Notifiers per stack
+	Notifiers map[string]notifier.Notifiers
 }
 
 var (
@@ -32,6 +35,31 @@ var (
 
 //Start function is the starting point of all processes.
 func Start() error {
+	switch command := builder.ParseCommand(); command {
+	case builder.DEPLOY_COMMAND:
+		return deploy()
+	case builder.SCHEMA_COMMAND:
+		return printSchema()
+	case builder.VALIDATE_COMMAND:
+		return validate()
+	default:
+		return fmt.Errorf("unknown command : %s", command)
+	}
+}
+
+//printSchema prints JSON schema of manifest file
+func printSchema() error {
+	schema, err := builder.GenerateSchema()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(schema))
+	return nil
+}
+
+//deploy runs the deployment process
+func deploy() error {
 	// Check OS first
 	//if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
 	//	return errors.New("you cannot run from local command.")
@@ -56,15 +84,15 @@ func Start() error {
 	}
 
 	// run with runner
-	return withRunner(builderSt, func(slacker tool.Slack) error {
+	return withRunner(builderSt, func(r Runner) error {
 		// These are post actions after deployment
-		slacker.SendSimpleMessage(":100: Deployment is done.", builderSt.Config.Env)
+		r.notify(builder.NOTIFY_EVENT_DONE, ":100: Deployment is done.")
 		return nil
 	})
 }
 
 //withRunner creates runner and runs the deployment process
-func withRunner(builder builder.Builder, postAction func(slacker tool.Slack) error) error {
+func withRunner(builder builder.Builder, postAction func(r Runner) error) error {
 	runner, err := NewRunner(builder)
 	if err != nil {
 		return err
@@ -75,7 +103,7 @@ func withRunner(builder builder.Builder, postAction func(slacker tool.Slack) err
 		return err
 	}
 
-	return postAction(runner.Slacker)
+	return postAction(runner)
 }
 
 // check validation for
@@ -86,10 +114,40 @@ func NewRunner(newBuilder builder.Builder) (Runner, error) {
 		Logger:    Logger.New(),
 		Builder:   newBuilder,
 		Collector: collector.NewCollector(newBuilder.MetricConfig, ""),
-		Slacker:   tool.NewSlackClient(newBuilder.Config.SlackOff),
+		Notifiers: newNotifiers(newBuilder),
 	}, nil
 }
 
+//newNotifiers creates notifiers of target stacks
+func newNotifiers(b builder.Builder) map[string]notifier.Notifiers {
+	ret := map[string]notifier.Notifiers{}
+	for _, stack := range b.Stacks {
+		if b.Config.Stack != "" && stack.Stack != b.Config.Stack {
+			continue
+		}
+		ret[stack.Stack] = notifier.New(stack, b.Config.SlackOff)
+	}
+
+	return ret
+}
+
+//notify sends the event of the whole deployment to notifiers of all target stacks
+//The same destination receives the event only once.
+func (r Runner) notify(eventType, message string) {
+	notifiers := []notifier.Notifiers{}
+	for _, stack := range r.Builder.Stacks {
+		if n, ok := r.Notifiers[stack.Stack]; ok {
+			notifiers = append(notifiers, n)
+		}
+	}
+
+	event := notifier.NewEvent(eventType, r.Builder.AwsConfig.Name, r.Builder.Config.Stack, r.Builder.Config.Env, "", "", message)
+	event.ReleaseNotes = builder.GetReleaseNotes(r.Builder.Config)
+	if err := notifier.Merge(notifiers...).Notify(event); err != nil {
+		r.Logger.Warn(err.Error())
+	}
+}
+
 // Set log format
 func (r Runner) LogFormatting(logLevel string) {
 	//logger.SetFormatter(&Logger.JSONFormatter{})
@@ -99,29 +157,14 @@ func (r Runner) LogFormatting(logLevel string) {
 
 // Run executes all required steps for deployments
 func (r Runner) Run() error {
-	defer func() {
-		if err := recover(); err != nil {
-			Logger.Error(err)
-			os.Exit(1)
-		}
-	}()
+	defer tool.RecoverExit()
 
 	//Send Beginning Message
 	r.Logger.Info("Beginning deployment: ", r.Builder.AwsConfig.Name)
 
 	msg := r.Builder.MakeSummary(r.Builder.Config.Stack)
 	fmt.Println(msg)
-	if r.Slacker.ValidClient() {
-		r.Logger.Debug("slack configuration is valid")
-		err := r.Slacker.SendSimpleMessage(msg, r.Builder.Config.Env)
-		if err != nil {
-			r.Logger.Warn(err.Error())
-			r.Slacker.SlackOff = true
-		}
-	} else {
-		// Slack variables are not set
-		r.Logger.Warnln("no slack variables exists. [ SLACK_TOKEN, SLACK_CHANNEL ]")
-	}
+	r.notify(builder.NOTIFY_EVENT_STARTED, msg)
 
 	if r.Builder.MetricConfig.Enabled {
 		r.Logger.Infof("Metric Measurement is enabled")
@@ -143,41 +186,92 @@ func (r Runner) Run() error {
 			Logger.Debugf("Skipping this stack, stack=%s", stack.Stack)
 			continue
 		}
-		d := getDeployer(r.Logger, stack, r.Builder.AwsConfig, r.Slacker, r.Collector)
+		d := getDeployer(r.Logger, stack, r.Builder.AwsConfig, r.Notifiers[stack.Stack], r.Collector)
 		deployers = append(deployers, d)
 	}
 
+	// Run on_failure callbacks when deployment fails
+	for _, d := range deployers {
+		d := d
+		tool.RegisterExitHook(fmt.Sprintf("on-failure:%s", d.GetStackName()), func() {
+			if err := d.TriggerCallbacks(r.Builder.Config, builder.CALLBACK_PHASE_ON_FAILURE); err != nil {
+				r.Logger.Errorln(err.Error())
+			}
+		})
+	}
+
+	// Notify the failure after on_failure callbacks
+	tool.RegisterExitHook("notify-failure", func() {
+		r.notify(builder.NOTIFY_EVENT_FAILED, fmt.Sprintf(":x: Deployment failed : %s", tool.GetExitReason()))
+	})
+
 	// Deploy
+	// Timeout of deploy phase is also checked in each region and while waiting for AMI copy
 	for _, deployer := range deployers {
+		if err := deployer.CheckPhaseTimeout(builder.PHASE_DEPLOY); err != nil {
+			return err
+		}
 		deployer.Deploy(r.Builder.Config)
+		if err := deployer.CheckPhaseTimeout(builder.PHASE_DEPLOY); err != nil {
+			return err
+		}
 	}
 
 	// healthcheck
-	doHealthchecking(deployers, r.Builder.Config)
+	if err := doHealthchecking(deployers, r.Builder.Config); err != nil {
+		return rollbackDeployers(deployers, r.Builder.Config, err)
+	}
+
+	// health gates
+	if err := doHealthGating(deployers, r.Builder.Config); err != nil {
+		return rollbackDeployers(deployers, r.Builder.Config, err)
+	}
+
+	// Post healthy callbacks
+	for _, deployer := range deployers {
+		if err := deployer.TriggerCallbacks(r.Builder.Config, builder.CALLBACK_PHASE_POST_HEALTHY); err != nil {
+			return err
+		}
+	}
 
 	// Attach scaling policy
 	for _, deployer := range deployers {
-		deployer.FinishAdditionalWork(r.Builder.Config)
+		if err := deployer.FinishAdditionalWork(r.Builder.Config); err != nil {
+			return err
+		}
 	}
 
 	// Trigger Lifecycle Callbacks
 	for _, deployer := range deployers {
-		deployer.TriggerLifecycleCallbacks(r.Builder.Config)
+		if err := deployer.TriggerLifecycleCallbacks(r.Builder.Config); err != nil {
+			return err
+		}
 	}
 
 	// Clear previous Version
 	for _, deployer := range deployers {
-		deployer.CleanPreviousVersion(r.Builder.Config)
+		if err := deployer.CleanPreviousVersion(r.Builder.Config); err != nil {
+			return err
+		}
 	}
 
 	// Checking all previous version before delete asg
-	cleanChecking(deployers, r.Builder.Config)
+	if err := cleanChecking(deployers, r.Builder.Config); err != nil {
+		return err
+	}
+
+	// Post cleanup callbacks
+	for _, deployer := range deployers {
+		if err := deployer.TriggerCallbacks(r.Builder.Config, builder.CALLBACK_PHASE_POST_CLEANUP); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
 //Generate new deployer
-func getDeployer(logger *Logger.Logger, stack builder.Stack, awsConfig builder.AWSConfig, slack tool.Slack, c collector.Collector) deployer.DeployManager {
+func getDeployer(logger *Logger.Logger, stack builder.Stack, awsConfig builder.AWSConfig, n notifier.Notifier, c collector.Collector) deployer.DeployManager {
 	deployer := deployer.NewBlueGrean(
 		stack.ReplacementType,
 		logger,
@@ -185,81 +279,158 @@ func getDeployer(logger *Logger.Logger, stack builder.Stack, awsConfig builder.A
 		stack,
 	)
 
-	deployer.Slack = slack
+	deployer.Notifier = n
 	deployer.Collector = c
 
 	return deployer
 }
 
+// pollResult is the result of polling a deployer
+type pollResult struct {
+	ret map[string]bool
+	err error
+}
+
+// rollbackDeployers rolls back other stacks when deployment of a stack failed
+// The failed stack is already rolled back, so its new autoscaling groups are not touched again.
+func rollbackDeployers(deployers []deployer.DeployManager, config builder.Config, err error) error {
+	for _, d := range deployers {
+		if rerr := d.RollbackOnFailure(config); rerr != nil {
+			Logger.Errorf("rollback of %s failed : %s", d.GetStackName(), rerr.Error())
+		}
+	}
+
+	return err
+}
+
 // doHealthchecking checks if newly deployed autoscaling group is healthy
-func doHealthchecking(deployers []deployer.DeployManager, config builder.Config) {
+func doHealthchecking(deployers []deployer.DeployManager, config builder.Config) error {
 	healthyStackList := []string{}
-	healthy := false
-
-	ch := make(chan map[string]bool)
+	nextPolls := map[string]time.Time{}
 
-	for !healthy {
-		count := 0
+	ch := make(chan pollResult)
 
+	for {
 		tool.CheckTimeout(config.StartTimestamp, config.Timeout)
 
-		for _, deployer := range deployers {
-			if tool.IsStringInArray(deployer.GetStackName(), healthyStackList) {
-				continue
-			}
-
-			count += 1
+		polled, err := pollableDeployers(deployers, healthyStackList, nextPolls, builder.PHASE_HEALTHCHECK)
+		if err != nil {
+			return err
+		}
 
+		for _, d := range polled {
 			//Start healthcheck thread
-			go func() {
-				ch <- deployer.HealthChecking(config)
-			}()
+			go func(d deployer.DeployManager) {
+				defer tool.RecoverExit()
+				ret, err := d.HealthChecking(config)
+				ch <- pollResult{ret: ret, err: err}
+			}(d)
 		}
 
-		for count > 0 {
-			ret := <-ch
-			for stack, fin := range ret {
+		errs := []string{}
+		for range polled {
+			result := <-ch
+			if result.err != nil {
+				errs = append(errs, result.err.Error())
+			}
+
+			for stack, fin := range result.ret {
 				if fin {
 					healthyStackList = append(healthyStackList, stack)
 				}
 			}
-			count -= 1
+		}
+
+		if len(errs) > 0 {
+			return fmt.Errorf("deployment failed in healthcheck : %s", strings.Join(errs, ", "))
 		}
 
 		if len(healthyStackList) == len(deployers) {
 			Logger.Info("All stacks are healthy")
-			healthy = true
-		} else {
-			Logger.Info("All stacks are not healthy... Please waiting to be deployed...")
-			time.Sleep(tool.POLLING_SLEEP_TIME)
+			return nil
 		}
+
+		Logger.Info("All stacks are not healthy... Please waiting to be deployed...")
+		sleepUntilNextPoll(polled, healthyStackList, nextPolls, builder.PHASE_HEALTHCHECK)
+	}
+}
+
+// doHealthGating checks if metrics of newly deployed autoscaling group are healthy
+func doHealthGating(deployers []deployer.DeployManager, config builder.Config) error {
+	passedStackList := []string{}
+	nextPolls := map[string]time.Time{}
+
+	ch := make(chan pollResult)
+
+	for {
+		tool.CheckTimeout(config.StartTimestamp, config.Timeout)
+
+		polled, err := pollableDeployers(deployers, passedStackList, nextPolls, builder.PHASE_HEALTH_GATE)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range polled {
+			//Start health gating thread
+			go func(d deployer.DeployManager) {
+				defer tool.RecoverExit()
+				ret, err := d.HealthGating(config)
+				ch <- pollResult{ret: ret, err: err}
+			}(d)
+		}
+
+		errs := []string{}
+		for range polled {
+			result := <-ch
+			if result.err != nil {
+				errs = append(errs, result.err.Error())
+			}
+
+			for stack, passed := range result.ret {
+				if passed {
+					passedStackList = append(passedStackList, stack)
+				}
+			}
+		}
+
+		if len(errs) > 0 {
+			return fmt.Errorf("deployment failed by health gates : %s", strings.Join(errs, ", "))
+		}
+
+		if len(passedStackList) == len(deployers) {
+			Logger.Info("All health gates are passed")
+			return nil
+		}
+
+		Logger.Info("Watching health gates... Please waiting to be deployed...")
+		sleepUntilNextPoll(polled, passedStackList, nextPolls, builder.PHASE_HEALTH_GATE)
 	}
 }
 
 // cleanChecking cleans old autoscaling groups
-func cleanChecking(deployers []deployer.DeployManager, config builder.Config) {
+func cleanChecking(deployers []deployer.DeployManager, config builder.Config) error {
 	doneStackList := []string{}
-	done := false
+	nextPolls := map[string]time.Time{}
 
 	ch := make(chan map[string]bool)
 
-	for !done {
-		count := 0
-
-		for _, deployer := range deployers {
-			if tool.IsStringInArray(deployer.GetStackName(), doneStackList) {
-				continue
-			}
+	for {
+		tool.CheckTimeout(config.StartTimestamp, config.Timeout)
 
-			count += 1
+		polled, err := pollableDeployers(deployers, doneStackList, nextPolls, builder.PHASE_TERMINATION)
+		if err != nil {
+			return err
+		}
 
+		for _, d := range polled {
 			//Start terminateChecking thread
-			go func() {
-				ch <- deployer.TerminateChecking(config)
-			}()
+			go func(d deployer.DeployManager) {
+				defer tool.RecoverExit()
+				ch <- d.TerminateChecking(config)
+			}(d)
 		}
 
-		for count > 0 {
+		for range polled {
 			ret := <-ch
 			for stack, fin := range ret {
 				if fin {
@@ -267,15 +438,63 @@ func cleanChecking(deployers []deployer.DeployManager, config builder.Config) {
 					doneStackList = append(doneStackList, stack)
 				}
 			}
-			count -= 1
 		}
 
 		if len(doneStackList) == len(deployers) {
 			Logger.Info("All stacks are terminated!!")
-			done = true
-		} else {
-			Logger.Info("All stacks are not ready to be terminated... Please waiting...")
-			time.Sleep(tool.POLLING_SLEEP_TIME)
+			return nil
+		}
+
+		Logger.Info("All stacks are not ready to be terminated... Please waiting...")
+		sleepUntilNextPoll(polled, doneStackList, nextPolls, builder.PHASE_TERMINATION)
+	}
+}
+
+// pollableDeployers returns deployers which should be polled now
+// It also checks timeout of the phase for deployers not finished.
+func pollableDeployers(deployers []deployer.DeployManager, finished []string, nextPolls map[string]time.Time, phase string) ([]deployer.DeployManager, error) {
+	now := time.Now()
+	ret := []deployer.DeployManager{}
+	for _, d := range deployers {
+		if tool.IsStringInArray(d.GetStackName(), finished) {
+			continue
+		}
+
+		if err := d.CheckPhaseTimeout(phase); err != nil {
+			return nil, err
+		}
+
+		if nextPolls[d.GetStackName()].After(now) {
+			continue
+		}
+
+		ret = append(ret, d)
+	}
+
+	return ret, nil
+}
+
+// sleepUntilNextPoll schedules the next poll of polled deployers and sleeps until the earliest one
+// Each stack could have different poll interval and backoff.
+func sleepUntilNextPoll(polled []deployer.DeployManager, finished []string, nextPolls map[string]time.Time, phase string) {
+	now := time.Now()
+	for _, d := range polled {
+		if !tool.IsStringInArray(d.GetStackName(), finished) {
+			nextPolls[d.GetStackName()] = now.Add(d.NextPollInterval(phase))
+		}
+	}
+
+	next := time.Time{}
+	for stack, t := range nextPolls {
+		if tool.IsStringInArray(stack, finished) {
+			continue
+		}
+
+		if next.IsZero() || t.Before(next) {
+			next = t
 		}
 	}
+
+	Logger.Debugf("next poll at %s", next.Format(time.RFC3339))
+	time.Sleep(time.Until(next))
 }
@@ -0,0 +1,206 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/DevopsArtFactory/goployer/pkg/aws"
+	"github.com/DevopsArtFactory/goployer/pkg/builder"
+	"github.com/DevopsArtFactory/goployer/pkg/tool"
+	Logger "github.com/sirupsen/logrus"
+)
+
+//validate checks the manifest and AWS resources of the stack without deployment
+func validate() error {
+	builderSt, err := builder.NewBuilder()
+	if err != nil {
+		return err
+	}
+
+	m, err := builder.ParseMetricConfig(builderSt.Config.DisableMetrics)
+	if err != nil {
+		return err
+	}
+
+	builderSt.MetricConfig = m
+
+	// Problems of configurations are reported together with ones of AWS resources
+	problems := []string{}
+	for _, err := range builderSt.ValidationErrors() {
+		problems = append(problems, err.Error())
+	}
+
+	for _, stack := range builderSt.Stacks {
+		if stack.Stack != builderSt.Config.Stack {
+			continue
+		}
+
+		for _, region := range stack.Regions {
+			if len(builderSt.Config.Region) > 0 && builderSt.Config.Region != region.Region {
+				continue
+			}
+
+			Logger.Infof("checking resources of %s in %s", stack.Stack, region.Region)
+			client := aws.BootstrapServices(region.Region, stack.AssumeRole)
+			for _, err := range validateRegion(client, builderSt.Config, stack, region) {
+				problems = append(problems, fmt.Sprintf("[%s/%s] %s", stack.Stack, region.Region, err.Error()))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			tool.Red(problem)
+		}
+		return fmt.Errorf("%d problems are found in stack %s", len(problems), builderSt.Config.Stack)
+	}
+
+	Logger.Infof("all resources of stack %s are valid", builderSt.Config.Stack)
+	return nil
+}
+
+//validateRegion checks if resources for the region exist and are compatible
+//It returns every problem found in the region.
+func validateRegion(client aws.AWSClient, config builder.Config, stack builder.Stack, region builder.RegionConfig) []error {
+	errs := []error{}
+	stack = builder.ApplyRegionOverrides(stack, region)
+
+	// Network
+	azs := region.AvailabilityZones
+	vpcId, err := client.EC2Service.FindVPCId(region.VPC)
+	if err != nil {
+		errs = append(errs, err)
+	} else {
+		if len(region.SecurityGroups) == 0 {
+			errs = append(errs, fmt.Errorf("Need to specify at least one security group"))
+		}
+
+		for _, sg := range region.SecurityGroups {
+			if _, err := client.EC2Service.FindSecurityGroupId(vpcId, sg); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		foundAzs, err := client.EC2Service.FindAvailabilityZones(vpcId, region.AvailabilityZones)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, az := range region.AvailabilityZones {
+				if !tool.IsStringInArray(az, foundAzs) {
+					errs = append(errs, fmt.Errorf("no subnet exists in availability zone : %s", az))
+				}
+			}
+			azs = foundAzs
+
+			subnets, err := client.EC2Service.FindSubnets(vpcId, region.UsePublicSubnets, azs)
+			if err != nil {
+				errs = append(errs, err)
+			} else if len(subnets) == 0 {
+				subnetType := "private"
+				if region.UsePublicSubnets {
+					subnetType = "public"
+				}
+				errs = append(errs, fmt.Errorf("no subnet tagged as %s exists in %s", subnetType, region.VPC))
+			}
+		}
+	}
+
+	// Load balancing
+	targetGroups := region.TargetGroups
+	if len(region.HealthcheckTargetGroup) > 0 && !tool.IsStringInArray(region.HealthcheckTargetGroup, targetGroups) {
+		targetGroups = append(targetGroups, region.HealthcheckTargetGroup)
+	}
+
+	for _, tg := range targetGroups {
+		if _, err := client.ELBService.FindTargetGroupARN(tg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	loadBalancers := region.LoadBalancers
+	if len(region.HealthcheckLB) > 0 && !tool.IsStringInArray(region.HealthcheckLB, loadBalancers) {
+		loadBalancers = append(loadBalancers, region.HealthcheckLB)
+	}
+
+	for _, lb := range loadBalancers {
+		if err := client.ClassicELBService.CheckLoadBalancer(lb); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Access
+	if len(region.SshKey) > 0 {
+		if err := client.EC2Service.CheckKeyPair(region.SshKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(stack.IamInstanceProfile) > 0 {
+		if err := client.IAMService.CheckInstanceProfile(stack.IamInstanceProfile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// AMI and instance types
+	architecture := ""
+	// If AMI is copied, then AMI in the source region is checked
+	ami := ""
+	amiClient := client
+	amiRegion := region
+	if builder.NeedAmiCopy(stack, region) {
+		sourceRegion := builder.GetAmiCopySourceRegion(stack)
+		for _, r := range stack.Regions {
+			if r.Region == sourceRegion {
+				amiRegion = r
+			}
+		}
+		amiClient = aws.BootstrapServices(sourceRegion, stack.AssumeRole)
+	}
+
+	amiId, amiSelector, err := builder.GetAmiSelector(config, amiRegion)
+	if err != nil {
+		errs = append(errs, err)
+	} else if ami, err = amiClient.ResolveAmi(amiId, amiSelector); err != nil {
+		errs = append(errs, err)
+	} else if image, err := amiClient.EC2Service.GetImage(ami); err != nil {
+		errs = append(errs, err)
+	} else {
+		architecture = *image.Architecture
+		if *image.State != "available" {
+			errs = append(errs, fmt.Errorf("AMI is not available : %s(%s)", ami, *image.State))
+		}
+	}
+
+	instanceTypes := []string{region.InstanceType}
+	if len(config.OverrideInstanceType) > 0 {
+		instanceTypes = []string{config.OverrideInstanceType}
+	}
+
+	if stack.MixedInstancesPolicy.Enabled {
+		instanceTypes = stack.MixedInstancesPolicy.Override
+	}
+
+	for _, instanceType := range instanceTypes {
+		archs, err := client.EC2Service.GetSupportedArchitectures(instanceType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if len(architecture) > 0 && !tool.IsStringInArray(architecture, archs) {
+			errs = append(errs, fmt.Errorf("instance type %s does not support architecture of AMI %s : %s", instanceType, ami, architecture))
+		}
+
+		offered, err := client.EC2Service.GetOfferedAvailabilityZones(instanceType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, az := range azs {
+			if !tool.IsStringInArray(az, offered) {
+				errs = append(errs, fmt.Errorf("instance type %s is not offered in %s", instanceType, az))
+			}
+		}
+	}
+
+	return errs
+}
@@ -27,16 +27,17 @@ func FileExists(filename string) bool {
 func ErrorLogging(msg string) {
 	if len(msg) == 0 {
 		Red(NO_ERROR_MESSAGE_PASSED)
-		os.Exit(1)
+		Exit(1)
 	}
 	Red(msg)
-	os.Exit(1)
+	SetExitReason(msg)
+	Exit(1)
 }
 
 // Fatal Error
 func FatalError(err error) {
+	RunExitHooks()
 	log.Fatalf("error: %v", err)
-	os.Exit(1)
 }
 
 func isZero(v reflect.Value) bool {
@@ -111,8 +112,8 @@ func CheckTimeout(start int64, timeout int64) bool {
 
 	//Over timeout
 	if (now - start) > timeoutSec {
-		Logger.Errorf("Timeout has been exceeded : %s minutes", timeout)
-		os.Exit(1)
+		Logger.Errorf("Timeout has been exceeded : %d minutes", timeout)
+		Exit(1)
 	}
 
 	return false
@@ -0,0 +1,101 @@
+package tool
+
+import (
+	"fmt"
+	Logger "github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	exitHooks     = map[string]func(){}
+	exitHookNames = []string{}
+	exitHookLock  sync.Mutex
+	exitReason    string
+)
+
+// RegisterExitHook registers a hook which runs when goployer exits with failure
+// Hook with the same name is replaced.
+func RegisterExitHook(name string, hook func()) {
+	exitHookLock.Lock()
+	defer exitHookLock.Unlock()
+
+	if _, ok := exitHooks[name]; !ok {
+		exitHookNames = append(exitHookNames, name)
+	}
+	exitHooks[name] = hook
+}
+
+// UnregisterExitHook removes the hook which is not needed anymore
+func UnregisterExitHook(name string) {
+	exitHookLock.Lock()
+	defer exitHookLock.Unlock()
+
+	delete(exitHooks, name)
+}
+
+// RunExitHooks runs registered hooks in order of registration
+// Hooks are cleared before running, so each hook runs only once even if a hook exits again.
+func RunExitHooks() {
+	exitHookLock.Lock()
+	hooks := []func(){}
+	for _, name := range exitHookNames {
+		if hook, ok := exitHooks[name]; ok {
+			hooks = append(hooks, hook)
+		}
+	}
+	exitHooks = map[string]func(){}
+	exitHookNames = []string{}
+	exitHookLock.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// SetExitReason keeps the reason of failure for exit hooks
+func SetExitReason(reason string) {
+	exitHookLock.Lock()
+	defer exitHookLock.Unlock()
+
+	exitReason = reason
+}
+
+// GetExitReason returns the reason of failure
+func GetExitReason() string {
+	exitHookLock.Lock()
+	defer exitHookLock.Unlock()
+
+	return exitReason
+}
+
+// Exit runs exit hooks and exits with the code
+func Exit(code int) {
+	RunExitHooks()
+	os.Exit(code)
+}
+
+// RecoverExit exits through exit hooks when panic happens
+// Panic is recovered only in its own goroutine, so this should be deferred in every goroutine.
+func RecoverExit() {
+	if err := recover(); err != nil {
+		Logger.Error(err)
+		SetExitReason(fmt.Sprint(err))
+		Exit(1)
+	}
+}
+
+// HandleExitSignals exits through exit hooks when goployer is interrupted or terminated
+func HandleExitSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		Logger.Errorf("goployer received signal : %s", sig)
+		SetExitReason(fmt.Sprintf("goployer received signal : %s", sig))
+		Exit(1)
+	}()
+}
@@ -59,8 +59,8 @@ func (s Slack) SendSimpleMessage(message string, env string) error {
 
 }
 
-func (s Slack) SendMessage(msgOpt slack.MsgOption) error {
-	_, _, _, err := s.Client.SendMessage(s.ChannelId, msgOpt)
+func (s Slack) SendMessage(msgOpts ...slack.MsgOption) error {
+	_, _, _, err := s.Client.SendMessage(s.ChannelId, msgOpts...)
 	if err != nil {
 		return err
 	}
@@ -68,6 +68,29 @@ func (s Slack) SendMessage(msgOpt slack.MsgOption) error {
 	return nil
 }
 
+// PostMessage posts a message, and returns the channel id and the timestamp of the message
+// Both are needed to update the message or to reply in its thread.
+func (s Slack) PostMessage(msgOpts ...slack.MsgOption) (string, string, error) {
+	return s.Client.PostMessage(s.ChannelId, msgOpts...)
+}
+
+// UpdateMessage updates the message of the timestamp in place
+func (s Slack) UpdateMessage(channelId, ts string, msgOpts ...slack.MsgOption) error {
+	_, _, _, err := s.Client.UpdateMessage(channelId, ts, msgOpts...)
+	return err
+}
+
+// SendThreadMessage replies to the thread of the message with the color of env
+func (s Slack) SendThreadMessage(channelId, ts, message, env string) error {
+	attachment := slack.Attachment{
+		Text:  message,
+		Color: colorMapping[env],
+	}
+
+	_, _, err := s.Client.PostMessage(channelId, slack.MsgOptionAttachments(attachment), slack.MsgOptionTS(ts))
+	return err
+}
+
 func (s Slack) CreateSimpleSection(text string) *slack.SectionBlock {
 	txt := slack.NewTextBlockObject("mrkdwn", text, false, false)
 	section := slack.NewSectionBlock(txt, nil, nil)